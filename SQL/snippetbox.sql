@@ -62,3 +62,173 @@ COMMIT;
 /*!40101 SET CHARACTER_SET_CLIENT=@OLD_CHARACTER_SET_CLIENT */;
 /*!40101 SET CHARACTER_SET_RESULTS=@OLD_CHARACTER_SET_RESULTS */;
 /*!40101 SET COLLATION_CONNECTION=@OLD_COLLATION_CONNECTION */;
+
+--
+-- Link every snippet to the user who created it
+--
+ALTER TABLE `snippets`
+  ADD `user_id` int NOT NULL AFTER `id`,
+  ADD KEY `idx_snippets_user_id` (`user_id`);
+
+--
+-- Snippets created before there were authors belong to the first user, the
+-- admin from DumbDB/snippetbox_users.sql, which has to be loaded first.
+-- Otherwise they keep user_id 0 and vanish from every page.
+--
+UPDATE `snippets` SET `user_id` = (SELECT MIN(`id`) FROM `users`) WHERE `user_id` = 0;
+
+--
+-- Keep deleted snippets in the trash until they are purged
+--
+ALTER TABLE `snippets`
+  ADD `deleted` datetime DEFAULT NULL AFTER `expires`;
+
+--
+-- Full-text search across snippet titles and content
+--
+ALTER TABLE `snippets`
+  ADD FULLTEXT KEY `idx_snippets_fulltext` (`title`, `content`);
+
+--
+-- Tags of snippets
+--
+CREATE TABLE `snippet_tags` (
+  `snippet_id` int NOT NULL,
+  `tag` varchar(30) COLLATE utf8mb4_unicode_ci NOT NULL,
+  PRIMARY KEY (`snippet_id`, `tag`),
+  KEY `idx_snippet_tags_tag` (`tag`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+--
+-- Language used to highlight the snippet content
+--
+ALTER TABLE `snippets`
+  ADD `language` varchar(20) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'plaintext' AFTER `content`;
+
+--
+-- Every version of every snippet, starting from the current ones
+--
+CREATE TABLE `snippet_revisions` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `snippet_id` int NOT NULL,
+  `user_id` int NOT NULL,
+  `title` varchar(100) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `content` text COLLATE utf8mb4_unicode_ci NOT NULL,
+  `language` varchar(20) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_snippet_revisions_snippet_id` (`snippet_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT INTO `snippet_revisions` (`snippet_id`, `user_id`, `title`, `content`, `language`, `created`)
+  SELECT `id`, `user_id`, `title`, `content`, `language`, `created` FROM `snippets` ORDER BY `id`;
+
+--
+-- Unlisted snippets are reachable only by their random share link slug
+--
+ALTER TABLE `snippets`
+  ADD `visibility` varchar(10) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'public' AFTER `language`,
+  ADD `slug` char(22) COLLATE utf8mb4_unicode_ci DEFAULT NULL AFTER `visibility`,
+  ADD UNIQUE KEY `idx_snippets_slug` (`slug`);
+
+--
+-- Optional bcrypt hash of the passphrase protecting the snippet content
+--
+ALTER TABLE `snippets`
+  ADD `hashed_passphrase` char(60) COLLATE utf8mb4_unicode_ci DEFAULT NULL AFTER `slug`;
+
+--
+-- Snippets with max_views set expire once they were viewed that many times
+--
+ALTER TABLE `snippets`
+  ADD `views` int NOT NULL DEFAULT 0 AFTER `hashed_passphrase`,
+  ADD `max_views` int DEFAULT NULL AFTER `views`;
+
+--
+-- Expired snippets are found by the reaper which removes them
+--
+ALTER TABLE `snippets`
+  ADD KEY `idx_snippets_expires` (`expires`);
+
+--
+-- Further files of multi-file snippets
+--
+CREATE TABLE `snippet_files` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `snippet_id` int NOT NULL,
+  `position` int NOT NULL,
+  `filename` varchar(100) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `language` varchar(20) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `content` text COLLATE utf8mb4_unicode_ci NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_snippet_files_snippet_id` (`snippet_id`, `position`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+--
+-- Forks refer to the snippet they were copied from
+--
+ALTER TABLE `snippets`
+  ADD `forked_from` int DEFAULT NULL AFTER `max_views`,
+  ADD KEY `idx_snippets_forked_from` (`forked_from`);
+
+--
+-- Snippets starred by users, every user stars a snippet at most once
+--
+CREATE TABLE `stars` (
+  `user_id` int NOT NULL,
+  `snippet_id` int NOT NULL,
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`user_id`, `snippet_id`),
+  KEY `idx_stars_snippet_id` (`snippet_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+--
+-- Comments on snippets, replies refer to the top-level comment they answer
+--
+CREATE TABLE `comments` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `snippet_id` int NOT NULL,
+  `parent_id` int DEFAULT NULL,
+  `user_id` int NOT NULL,
+  `content` text COLLATE utf8mb4_unicode_ci NOT NULL,
+  `created` datetime NOT NULL,
+  `updated` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_comments_snippet_id` (`snippet_id`, `created`),
+  KEY `idx_comments_parent_id` (`parent_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+--
+-- Daily view counts of snippets for the analytics
+--
+CREATE TABLE `snippet_views` (
+  `snippet_id` int NOT NULL,
+  `day` date NOT NULL,
+  `views` int NOT NULL,
+  PRIMARY KEY (`snippet_id`, `day`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+--
+-- Named collections of snippets put together by users
+--
+CREATE TABLE `collections` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `title` varchar(100) COLLATE utf8mb4_unicode_ci NOT NULL,
+  `description` text COLLATE utf8mb4_unicode_ci NOT NULL,
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_collections_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+--
+-- Snippets of the collections, listed by position
+--
+CREATE TABLE `collection_snippets` (
+  `collection_id` int NOT NULL,
+  `snippet_id` int NOT NULL,
+  `position` int NOT NULL,
+  PRIMARY KEY (`collection_id`, `snippet_id`),
+  KEY `idx_collection_snippets_position` (`collection_id`, `position`),
+  KEY `idx_collection_snippets_snippet_id` (`snippet_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/forms"
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+// Longest collection description in characters
+const maxDescriptionLength = 1000
+
+// Check the title and description fields of the collection forms
+func validateCollectionForm(form *forms.Form) {
+	form.Required("title")
+	form.MaxLength("title", 100)
+	form.MaxLength("description", maxDescriptionLength)
+}
+
+// Return the URL of the public collection page
+func collectionURL(c *models.Collection) string {
+	return fmt.Sprintf("/c/%d", c.ID)
+}
+
+// The collection helper fetches the collection from the :id URL parameter.
+// If there is no such collection, the relevant error response is sent and
+// false is returned.
+func (app *application) collection(w http.ResponseWriter, r *http.Request) (*models.Collection, bool) {
+	id, err := strconv.Atoi(r.URL.Query().Get(":id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return nil, false
+	}
+
+	c, err := app.collections.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return c, true
+}
+
+// The ownCollection helper fetches the collection like the collection
+// helper and checks that it belongs to the authenticated user
+func (app *application) ownCollection(w http.ResponseWriter, r *http.Request) (*models.Collection, bool) {
+	c, ok := app.collection(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if c.UserID != app.authenticatedUser(r).ID {
+		app.clientError(w, http.StatusForbidden)
+		return nil, false
+	}
+
+	return c, true
+}
+
+// Return the ID of the snippet posted in the snippet field of a collection
+// form or 0 if it's invalid
+func postedSnippetID(r *http.Request) int {
+	id, err := strconv.Atoi(r.PostForm.Get("snippet"))
+	if err != nil || id < 1 {
+		return 0
+	}
+
+	return id
+}
+
+// User collections GET /user/collections
+func (app *application) userCollections(w http.ResponseWriter, r *http.Request) {
+	c, err := app.collections.ByUser(app.authenticatedUser(r).ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.render(w, r, "collections.page.html", &templateData{
+		Collections: c,
+	})
+}
+
+// Create collection GET /collection/create
+func (app *application) createCollectionForm(w http.ResponseWriter, r *http.Request) {
+	app.render(w, r, "collection.page.html", &templateData{
+		Form: forms.New(nil),
+	})
+}
+
+// Create collection POST /collection/create
+func (app *application) createCollection(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	validateCollectionForm(form)
+
+	if !form.Valid() {
+		app.render(w, r, "collection.page.html", &templateData{Form: form})
+		return
+	}
+
+	c := &models.Collection{
+		UserID:      app.authenticatedUser(r).ID,
+		Title:       form.Get("title"),
+		Description: form.Get("description"),
+	}
+
+	c.ID, err = app.collections.Insert(c)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.session.Put(r, "flash", "Collection successfully created")
+
+	http.Redirect(w, r, collectionURL(c), http.StatusSeeOther)
+}
+
+// Show collection GET /c/:id
+func (app *application) showCollection(w http.ResponseWriter, r *http.Request) {
+	c, ok := app.collection(w, r)
+	if !ok {
+		return
+	}
+
+	snippets, err := app.collections.Snippets(c.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// Unlisted snippets are only listed for their authors, like they are
+	// only found by ID for them
+	var listed []*models.Snippet
+	for _, s := range snippets {
+		if !s.Unlisted() || app.isAuthor(r, s) {
+			listed = append(listed, s)
+		}
+	}
+
+	app.render(w, r, "showcollection.page.html", &templateData{
+		Collection: c,
+		Snippets:   listed,
+	})
+}
+
+// Render the edit page of the collection with its snippets, which the owner
+// can reorder and remove there. Unlisted snippets of other users are shown
+// without a link, which would give away their slug.
+func (app *application) renderEditCollection(w http.ResponseWriter, r *http.Request, c *models.Collection, form *forms.Form) {
+	snippets, err := app.collections.Snippets(c.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.render(w, r, "collection.page.html", &templateData{
+		Collection: c,
+		Form:       form,
+		Snippets:   snippets,
+	})
+}
+
+// Edit collection GET /collection/:id/edit
+func (app *application) editCollectionForm(w http.ResponseWriter, r *http.Request) {
+	c, ok := app.ownCollection(w, r)
+	if !ok {
+		return
+	}
+
+	app.renderEditCollection(w, r, c, forms.New(url.Values{
+		"title":       {c.Title},
+		"description": {c.Description},
+	}))
+}
+
+// Edit collection POST /collection/:id/edit
+func (app *application) editCollection(w http.ResponseWriter, r *http.Request) {
+	c, ok := app.ownCollection(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	validateCollectionForm(form)
+
+	if !form.Valid() {
+		app.renderEditCollection(w, r, c, form)
+		return
+	}
+
+	err = app.collections.Update(&models.Collection{
+		ID:          c.ID,
+		Title:       form.Get("title"),
+		Description: form.Get("description"),
+	})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.session.Put(r, "flash", "Collection successfully updated")
+
+	http.Redirect(w, r, collectionURL(c), http.StatusSeeOther)
+}
+
+// Delete collection POST /collection/:id/delete
+func (app *application) deleteCollection(w http.ResponseWriter, r *http.Request) {
+	c, ok := app.ownCollection(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.collections.Delete(c.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.session.Put(r, "flash", "Collection successfully deleted")
+
+	http.Redirect(w, r, "/user/collections", http.StatusSeeOther)
+}
+
+// Move snippet of collection POST /collection/:id/move. The direction field
+// is "up" or "down".
+func (app *application) moveCollectionSnippet(w http.ResponseWriter, r *http.Request) {
+	c, ok := app.ownCollection(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	id := postedSnippetID(r)
+	direction := r.PostForm.Get("direction")
+	if id == 0 || (direction != "up" && direction != "down") {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = app.collections.Move(c.ID, id, direction == "up")
+	if errors.Is(err, models.ErrNoRecord) {
+		app.notFound(w)
+		return
+	} else if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/collection/%d/edit", c.ID), http.StatusSeeOther)
+}
+
+// Remove snippet from collection POST /collection/:id/remove
+func (app *application) removeCollectionSnippet(w http.ResponseWriter, r *http.Request) {
+	c, ok := app.ownCollection(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	id := postedSnippetID(r)
+	if id == 0 {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = app.collections.Remove(c.ID, id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.session.Put(r, "flash", "Snippet removed from the collection")
+
+	http.Redirect(w, r, fmt.Sprintf("/collection/%d/edit", c.ID), http.StatusSeeOther)
+}
+
+// Add snippet to collection POST /snippet/:id/collect and
+// POST /s/:slug/collect. The collection field has the ID of a collection of
+// the authenticated user.
+func (app *application) collectSnippet(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.requestedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PostForm.Get("collection"))
+	if err != nil || id < 1 {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	c, err := app.collections.Get(id)
+	if errors.Is(err, models.ErrNoRecord) {
+		app.notFound(w)
+		return
+	} else if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	if c.UserID != app.authenticatedUser(r).ID {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	err = app.collections.Add(c.ID, s.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.session.Put(r, "flash", fmt.Sprintf("Snippet added to the collection %q", c.Title))
+
+	http.Redirect(w, r, snippetURL(s), http.StatusSeeOther)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+// Longest file name stem built from a snippet title
+const maxFilenameStem = 50
+
+// Return the download file name of the snippet built from its title and
+// language, e.g. "hello-world.go"
+func snippetFilename(s *models.Snippet) string {
+	return filenameStem(s) + languageExt(s.Language)
+}
+
+// Return the file name of the snippet without extension built from its
+// title. Snippets without letters or digits in the title are named by their
+// ID.
+func filenameStem(s *models.Snippet) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s.Title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+
+	stem := []rune(b.String())
+	if len(stem) > maxFilenameStem {
+		stem = []rune(strings.TrimRight(string(stem[:maxFilenameStem]), "-"))
+	}
+	if len(stem) == 0 {
+		return fmt.Sprintf("snippet-%d", s.ID)
+	}
+
+	return string(stem)
+}
+
+// Return the name, or the name with a number added if it's already taken
+func uniqueName(name string, taken map[string]bool) string {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for n := 2; taken[name]; n++ {
+		name = fmt.Sprintf("%s-%d%s", stem, n, ext)
+	}
+	taken[name] = true
+	return name
+}
+
+// Return the lines of the content selected by spec, a 1-based line number or
+// a range like 10-20. Ranges past the last line end at it. The second value
+// is false if spec is invalid or starts past the last line.
+func selectLines(content, spec string) (string, bool) {
+	bounds := strings.SplitN(spec, "-", 2)
+	from, err := strconv.Atoi(bounds[0])
+	if err != nil {
+		return "", false
+	}
+	to := from
+	if len(bounds) == 2 {
+		to, err = strconv.Atoi(bounds[1])
+		if err != nil {
+			return "", false
+		}
+	}
+	if from < 1 || to < from {
+		return "", false
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if from > len(lines) {
+		return "", false
+	}
+	if to > len(lines) {
+		to = len(lines)
+	}
+
+	return strings.Join(lines[from-1:to], ""), true
+}
+
+// Write the content of the snippet as plain text, as a file attachment if
+// download is true. The lines query parameter selects a range of lines, see
+// selectLines. Showing the content counts as a view of the snippet.
+func (app *application) serveRaw(w http.ResponseWriter, r *http.Request, s *models.Snippet, download bool) {
+	// There is no passphrase form for plain text
+	if app.locked(r, s) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	content := s.Content
+	if spec := r.URL.Query().Get("lines"); spec != "" {
+		var ok bool
+		content, ok = selectLines(content, spec)
+		if !ok {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	s, ok := app.view(w, r, s)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if download {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+			"filename": snippetFilename(s),
+		}))
+	}
+
+	io.WriteString(w, content)
+}
+
+// Write the snippet content and all its files as a ZIP archive attachment.
+// Showing the content counts as a view of the snippet.
+func (app *application) serveZip(w http.ResponseWriter, r *http.Request, s *models.Snippet) {
+	if app.locked(r, s) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	s, ok := app.view(w, r, s)
+	if !ok {
+		return
+	}
+
+	// The content is the first file, named after the snippet
+	files := append([]*models.File{{Name: snippetFilename(s), Content: s.Content}}, s.Files...)
+
+	// Build the archive in memory, so an error can still be reported
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	taken := map[string]bool{}
+	for _, f := range files {
+		fw, err := zw.Create(uniqueName(f.Name, taken))
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		_, err = io.WriteString(fw, f.Content)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+	err := zw.Close()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": filenameStem(s) + ".zip",
+	}))
+	buf.WriteTo(w)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+func TestSnippetFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    string
+		language string
+		want     string
+	}{
+		{"Words", "Hello, World!", "go", "hello-world.go"},
+		{"Spaces around", "  nginx config  ", "plaintext", "nginx-config.txt"},
+		{"Unicode", "Привет мир", "python", "привет-мир.py"},
+		{"Unknown language", "notes", "cobol", "notes.txt"},
+		{"No letters", "!!!", "bash", "snippet-7.sh"},
+		{"Long", "a very long title which has to be cut before it gets too long for a file", "sql",
+			"a-very-long-title-which-has-to-be-cut-before-it-ge.sql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &models.Snippet{ID: 7, Title: tt.title, Language: tt.language}
+
+			got := snippetFilename(s)
+			if got != tt.want {
+				t.Errorf("want %q; got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSelectLines(t *testing.T) {
+	content := "one\ntwo\nthree\n"
+
+	tests := []struct {
+		spec   string
+		want   string
+		wantOK bool
+	}{
+		{"1", "one\n", true},
+		{"2-3", "two\nthree\n", true},
+		{"2-2", "two\n", true},
+		{"3-10", "three\n", true},
+		{"4", "", false},
+		{"0", "", false},
+		{"3-2", "", false},
+		{"-1", "", false},
+		{"1-", "", false},
+		{"a-b", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, ok := selectLines(content, tt.spec)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("want %q, %v; got %q, %v", tt.want, tt.wantOK, got, ok)
+			}
+		})
+	}
+
+	// The last line doesn't need a line break
+	if got, _ := selectLines("one\ntwo", "2"); got != "two" {
+		t.Errorf("want %q; got %q", "two", got)
+	}
+}
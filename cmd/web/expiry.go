@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/forms"
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+// Expiry policies of the snippet forms
+const (
+	expiryDuration = "duration"
+	expiryDate     = "date"
+	expiryNever    = "never"
+)
+
+// Format of the expires_at field, the value of a datetime-local input
+const expiryDateLayout = "2006-01-02T15:04"
+
+// Units of the expires_in field
+var expiryUnits = map[string]time.Duration{
+	"minutes": time.Minute,
+	"hours":   time.Hour,
+	"days":    24 * time.Hour,
+}
+
+// Return the expiry fields preselected on the snippet forms
+func defaultExpiry() url.Values {
+	return url.Values{
+		"expiry":       {expiryDuration},
+		"expires_in":   {"7"},
+		"expires_unit": {"days"},
+	}
+}
+
+// Check the expiry fields of the form and return the time the snippet
+// expires at. A duration or a date must be within the configured bounds
+// from now, "never" returns models.Never.
+func (app *application) validateExpiry(form *forms.Form, now time.Time) time.Time {
+	form.Required("expiry")
+	form.PermittedValues("expiry", expiryDuration, expiryDate, expiryNever)
+
+	var expires time.Time
+	field := ""
+
+	switch form.Get("expiry") {
+	case expiryNever:
+		return models.Never
+	case expiryDuration:
+		field = "expires_in"
+		form.Required(field, "expires_unit")
+		form.IntRange(field, 1, 1000000)
+		form.PermittedValues("expires_unit", "minutes", "hours", "days")
+		if form.Errors.Get(field) != "" || form.Errors.Get("expires_unit") != "" {
+			return expires
+		}
+
+		n, _ := strconv.Atoi(form.Get(field))
+		unit := expiryUnits[form.Get("expires_unit")]
+		// Check the bound before multiplying, a large count of days
+		// overflows the duration and wraps around into the bounds
+		if time.Duration(n) > app.maxExpiry/unit {
+			form.Errors.Add(field, app.expiryRangeError())
+			return expires
+		}
+		expires = now.Add(time.Duration(n) * unit)
+	case expiryDate:
+		field = "expires_at"
+		form.Required(field)
+		if form.Errors.Get(field) != "" {
+			return expires
+		}
+
+		t, err := time.ParseInLocation(expiryDateLayout, form.Get(field), time.UTC)
+		if err != nil {
+			form.Errors.Add(field, "This field is invalid")
+			return expires
+		}
+		expires = t
+	default:
+		return expires
+	}
+
+	d := expires.Sub(now)
+	if d < app.minExpiry || d > app.maxExpiry {
+		form.Errors.Add(field, app.expiryRangeError())
+	}
+
+	return expires
+}
+
+// Return the error message of an expiry out of the configured bounds
+func (app *application) expiryRangeError() string {
+	return fmt.Sprintf("The snippet must expire in %s to %s",
+		humanDuration(app.minExpiry), humanDuration(app.maxExpiry))
+}
+
+// Return the duration in the largest whole unit of expiryUnits
+func humanDuration(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d%time.Hour == 0:
+		return plural(int(d/time.Hour), "hour")
+	default:
+		return plural(int(d/time.Minute), "minute")
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+
+	return fmt.Sprintf("%d %ss", n, unit)
+}
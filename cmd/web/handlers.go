@@ -4,11 +4,15 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/alekslesik/snippetbox.learn/pkg/diff"
 	"github.com/alekslesik/snippetbox.learn/pkg/forms"
 	"github.com/alekslesik/snippetbox.learn/pkg/models"
 
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Ping GET /ping
@@ -24,8 +28,100 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tags, err := app.snippets.TagCloud()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
 	app.render(w, r, "home.page.html", &templateData{
 		Snippets: s,
+		Tags:     tags,
+	})
+}
+
+// Snippets archive GET /snippets
+func (app *application) archive(w http.ResponseWriter, r *http.Request) {
+	page, ok := pageParam(r)
+	if !ok {
+		app.notFound(w)
+		return
+	}
+
+	s, total, err := app.snippets.Archive(snippetsPerPage, (page-1)*snippetsPerPage)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// Pages after the last one don't exist
+	p := newPagination(r.URL, page, total)
+	if page > p.Last {
+		app.notFound(w)
+		return
+	}
+
+	app.render(w, r, "archive.page.html", &templateData{
+		Pagination: p,
+		Snippets:   s,
+	})
+}
+
+// Search snippets GET /search?q=
+func (app *application) search(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	// Without a query only the search form is shown
+	if q == "" {
+		app.render(w, r, "search.page.html", &templateData{})
+		return
+	}
+
+	page, ok := pageParam(r)
+	if !ok {
+		app.notFound(w)
+		return
+	}
+
+	s, total, err := app.snippets.Search(q, snippetsPerPage, (page-1)*snippetsPerPage)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.render(w, r, "search.page.html", &templateData{
+		Pagination: newPagination(r.URL, page, total),
+		Query:      q,
+		Snippets:   s,
+	})
+}
+
+// Snippets with the tag GET /tag/:name
+func (app *application) showTag(w http.ResponseWriter, r *http.Request) {
+	tag := r.URL.Query().Get(":name")
+
+	page, ok := pageParam(r)
+	if !ok {
+		app.notFound(w)
+		return
+	}
+
+	s, total, err := app.snippets.ByTag(tag, snippetsPerPage, (page-1)*snippetsPerPage)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	// Unknown tags and pages after the last one don't exist
+	p := newPagination(r.URL, page, total)
+	if total == 0 || page > p.Last {
+		app.notFound(w)
+		return
+	}
+
+	app.render(w, r, "tag.page.html", &templateData{
+		Pagination: p,
+		Snippets:   s,
+		Tag:        tag,
 	})
 }
 
@@ -73,7 +169,7 @@ func (app *application) loginUser(w http.ResponseWriter, r *http.Request) {
 func (app *application) createSnippetForm(w http.ResponseWriter, r *http.Request) {
 	app.render(w, r, "create.page.html", &templateData{
 		// pass a new empty forms.Form object to the template
-		Form: forms.New(nil),
+		Form: forms.New(defaultExpiry()),
 	})
 }
 
@@ -89,9 +185,8 @@ func (app *application) createSnippet(w http.ResponseWriter, r *http.Request) {
 	// Create forms.Form containing the POSTed data from the form
 	form := forms.New(r.PostForm)
 	// Use validation functions
-	form.Required("title", "content", "expires")
-	form.MaxLength("title", 100)
-	form.PermittedValues("expires", "365", "7", "1")
+	validateSnippetForm(form)
+	expires := app.validateExpiry(form, time.Now())
 
 	// if any errors, redisplay the create.page.html paasingvalidation errors and
 	// previously submitted r.PostForm data
@@ -102,7 +197,21 @@ func (app *application) createSnippet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := app.snippets.Insert(form.Get("title"), form.Get("content"), form.Get("expires"))
+	// Validated above, an empty field means no limit of views
+	maxViews, _ := strconv.Atoi(form.Get("max_views"))
+
+	id, err := app.snippets.Insert(&models.Snippet{
+		UserID:     app.authenticatedUser(r).ID,
+		Title:      form.Get("title"),
+		Content:    form.Get("content"),
+		Language:   form.Get("language"),
+		Visibility: form.Get("visibility"),
+		Tags:       forms.SplitTags(form.Get("tags")),
+		Files:      formFiles(form),
+		Passphrase: form.Get("passphrase"),
+		MaxViews:   maxViews,
+		Expires:    expires,
+	})
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -117,13 +226,442 @@ func (app *application) createSnippet(w http.ResponseWriter, r *http.Request) {
 
 // Show snippet GET /snippet/:id
 func (app *application) showSnippet(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.snippet(w, r)
+	if !ok {
+		return
+	}
+
+	app.renderSnippet(w, r, s)
+}
+
+// Show unlisted snippet GET /s/:slug
+func (app *application) showSharedSnippet(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.sharedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	app.renderSnippet(w, r, s)
+}
+
+// Snippet as plain text GET /snippet/:id/raw and GET /s/:slug/raw
+func (app *application) rawSnippet(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.requestedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	app.serveRaw(w, r, s, false)
+}
+
+// Snippet as a file GET /snippet/:id/download and GET /s/:slug/download
+func (app *application) downloadSnippet(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.requestedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	app.serveRaw(w, r, s, true)
+}
+
+// All snippet files as a ZIP archive GET /snippet/:id/zip and GET /s/:slug/zip
+func (app *application) zipSnippet(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.requestedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	app.serveZip(w, r, s)
+}
+
+// Snippet for framing by other sites GET /snippet/:id/embed and
+// GET /s/:slug/embed
+func (app *application) embedSnippet(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.requestedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// There is no passphrase form in the embed layout
+	if app.locked(r, s) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	s, ok = app.view(w, r, s)
+	if !ok {
+		return
+	}
+
+	app.render(w, r, "embed.page.html", &templateData{
+		Snippet: s,
+	})
+}
+
+// Unlock protected snippet POST /snippet/:id/unlock
+func (app *application) unlockSnippet(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.snippet(w, r)
+	if !ok {
+		return
+	}
+
+	app.unlock(w, r, s)
+}
+
+// Unlock protected unlisted snippet POST /s/:slug/unlock
+func (app *application) unlockSharedSnippet(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.sharedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	app.unlock(w, r, s)
+}
+
+// Fork snippet POST /snippet/:id/fork and POST /s/:slug/fork
+func (app *application) forkSnippet(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.requestedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	if app.locked(r, s) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	// Copying the content counts as a view, the fork keeps the expiry the
+	// original had before
+	v, ok := app.view(w, r, s)
+	if !ok {
+		return
+	}
+
+	id, err := app.snippets.Insert(&models.Snippet{
+		UserID:     app.authenticatedUser(r).ID,
+		Title:      v.Title,
+		Content:    v.Content,
+		Language:   v.Language,
+		Visibility: v.Visibility,
+		Tags:       v.Tags,
+		Files:      v.Files,
+		Expires:    s.Expires,
+		ForkedFrom: v.ID,
+	})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.session.Put(r, "flash", "Snippet sucessfully forked")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/%d", id), http.StatusSeeOther)
+}
+
+// Star or unstar snippet POST /snippet/:id/star and POST /s/:slug/star. The
+// posted star field sets the state, so repeating the request changes nothing.
+func (app *application) starSnippet(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.requestedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	star, err := strconv.ParseBool(r.PostForm.Get("star"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = app.snippets.Star(s.ID, app.authenticatedUser(r).ID, star)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, snippetURL(s), http.StatusSeeOther)
+}
+
+// Comment on snippet POST /snippet/:id/comment and POST /s/:slug/comment.
+// Replies have the ID of the comment they answer in the parent_id field.
+func (app *application) commentSnippet(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.requestedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	if app.locked(r, s) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	parentID := 0
+	if v := r.PostForm.Get("parent_id"); v != "" {
+		parentID, err = strconv.Atoi(v)
+		if err != nil || parentID < 1 {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	form := forms.New(r.PostForm)
+	validateCommentForm(form)
+
+	if !form.Valid() {
+		// The form is shown with the content, which counts as a view
+		s, ok := app.view(w, r, s)
+		if !ok {
+			return
+		}
+
+		app.renderShow(w, r, &templateData{
+			CommentForm: form,
+			Snippet:     s,
+		})
+		return
+	}
+
+	id, err := app.comments.Insert(&models.Comment{
+		SnippetID: s.ID,
+		ParentID:  parentID,
+		UserID:    app.authenticatedUser(r).ID,
+		Content:   form.Get("content"),
+	})
+	if err != nil {
+		// Only top-level comments of the snippet can be replied to
+		if errors.Is(err, models.ErrNoRecord) {
+			app.clientError(w, http.StatusBadRequest)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.session.Put(r, "flash", "Comment sucessfully added")
+
+	http.Redirect(w, r, commentURL(s, id), http.StatusSeeOther)
+}
+
+// Edit comment GET /comment/:id/edit
+func (app *application) editCommentForm(w http.ResponseWriter, r *http.Request) {
+	c, s, ok := app.ownComment(w, r)
+	if !ok {
+		return
+	}
+
+	app.render(w, r, "comment.page.html", &templateData{
+		Comment: c,
+		Form:    forms.New(url.Values{"content": {c.Content}}),
+		Snippet: s,
+	})
+}
+
+// Edit comment POST /comment/:id/edit
+func (app *application) editComment(w http.ResponseWriter, r *http.Request) {
+	c, s, ok := app.ownComment(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	validateCommentForm(form)
+
+	if !form.Valid() {
+		app.render(w, r, "comment.page.html", &templateData{
+			Comment: c,
+			Form:    form,
+			Snippet: s,
+		})
+		return
+	}
+
+	err = app.comments.Update(c.ID, form.Get("content"))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.session.Put(r, "flash", "Comment sucessfully updated")
+
+	http.Redirect(w, r, commentURL(s, c.ID), http.StatusSeeOther)
+}
+
+// Delete comment together with its replies POST /comment/:id/delete
+func (app *application) deleteComment(w http.ResponseWriter, r *http.Request) {
+	c, s, ok := app.ownComment(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.comments.Delete(c.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.session.Put(r, "flash", "Comment sucessfully deleted")
+
+	http.Redirect(w, r, snippetURL(s)+"#comments", http.StatusSeeOther)
+}
+
+// Edit snippet GET /snippet/:id/edit
+func (app *application) editSnippetForm(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.ownSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// Prefill the form with the current snippet data
+	data := url.Values{
+		"title":      {s.Title},
+		"content":    {s.Content},
+		"language":   {s.Language},
+		"tags":       {strings.Join(s.Tags, ", ")},
+		"visibility": {s.Visibility},
+	}
+	for _, f := range s.Files {
+		data.Add("file_name", f.Name)
+		data.Add("file_language", f.Language)
+		data.Add("file_content", f.Content)
+	}
+
+	app.render(w, r, "edit.page.html", &templateData{
+		Form:    forms.New(data),
+		Snippet: s,
+	})
+}
+
+// Edit snippet POST /snippet/:id/edit
+func (app *application) editSnippet(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.ownSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	validateSnippetForm(form)
+
+	if !form.Valid() {
+		app.render(w, r, "edit.page.html", &templateData{
+			Form:    form,
+			Snippet: s,
+		})
+		return
+	}
+
+	err = app.snippets.Update(&models.Snippet{
+		ID:         s.ID,
+		UserID:     app.authenticatedUser(r).ID,
+		Title:      form.Get("title"),
+		Content:    form.Get("content"),
+		Language:   form.Get("language"),
+		Visibility: form.Get("visibility"),
+		Tags:       forms.SplitTags(form.Get("tags")),
+		Files:      formFiles(form),
+	})
+	if errors.Is(err, models.ErrNoRecord) {
+		// The snippet expired or was deleted since it was looked up
+		app.notFound(w)
+		return
+	} else if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.session.Put(r, "flash", "Snippet sucessfully updated")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/%d", s.ID), http.StatusSeeOther)
+}
+
+// Extend snippet expiry POST /snippet/:id/extend
+func (app *application) extendSnippet(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.ownSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	expires := app.validateExpiry(form, time.Now())
+	if form.Valid() && !expires.After(s.Expires) {
+		form.Errors.Add("expiry", "The snippet already expires later")
+	}
+
+	if !form.Valid() {
+		app.renderShow(w, r, &templateData{
+			Form:    form,
+			Snippet: s,
+		})
+		return
+	}
+
+	err = app.snippets.Extend(s.ID, expires)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.session.Put(r, "flash", "Snippet expiry sucessfully extended")
+
+	http.Redirect(w, r, snippetURL(s), http.StatusSeeOther)
+}
+
+// Delete snippet POST /snippet/:id/delete
+func (app *application) deleteSnippet(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.ownSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.Delete(s.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.session.Put(r, "flash", "Snippet moved to the trash")
+
+	http.Redirect(w, r, "/user/trash", http.StatusSeeOther)
+}
+
+// Restore snippet POST /snippet/:id/restore
+func (app *application) restoreSnippet(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(r.URL.Query().Get(":id"))
 	if err != nil || id < 1 {
 		app.notFound(w)
 		return
 	}
 
-	s, err := app.snippets.Get(id)
+	// Only snippets from the trash of the current user can be restored
+	err = app.snippets.Restore(id, app.authenticatedUser(r).ID)
 	if err != nil {
 		if errors.Is(err, models.ErrNoRecord) {
 			app.notFound(w)
@@ -133,11 +671,215 @@ func (app *application) showSnippet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	app.render(w, r, "show.page.html", &templateData{
+	app.session.Put(r, "flash", "Snippet sucessfully restored")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/%d", id), http.StatusSeeOther)
+}
+
+// Trash of the current user GET /user/trash
+func (app *application) userTrash(w http.ResponseWriter, r *http.Request) {
+	s, err := app.snippets.Trash(app.authenticatedUser(r).ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.render(w, r, "trash.page.html", &templateData{
+		Snippets: s,
+	})
+}
+
+// Snippets of the current user GET /user/snippets
+func (app *application) userSnippets(w http.ResponseWriter, r *http.Request) {
+	s, err := app.snippets.ByUser(app.authenticatedUser(r).ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.render(w, r, "mysnippets.page.html", &templateData{
+		Snippets: s,
+	})
+}
+
+// Daily views of the snippet for its author GET /snippet/:id/stats
+func (app *application) snippetStats(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.ownSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	views, err := app.snippets.DailyViews(s.ID, day(now).AddDate(0, 0, 1-statsDays))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.render(w, r, "stats.page.html", &templateData{
+		Chart:   viewsChart(views, now, statsDays),
 		Snippet: s,
 	})
 }
 
+// Starred snippets of the user GET /user/starred
+func (app *application) userStarred(w http.ResponseWriter, r *http.Request) {
+	s, err := app.snippets.Starred(app.authenticatedUser(r).ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.render(w, r, "starred.page.html", &templateData{
+		Snippets: s,
+	})
+}
+
+// Number of unchanged lines shown around every change of a diff
+const diffContext = 3
+
+// Snippet history GET /snippet/:id/history
+func (app *application) snippetHistory(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.snippet(w, r)
+	if !ok {
+		return
+	}
+
+	// The history reveals the content, so it needs the passphrase too
+	if app.locked(r, s) {
+		http.Redirect(w, r, snippetURL(s), http.StatusSeeOther)
+		return
+	}
+	if app.historyHidden(r, s) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	revisions, err := app.snippets.Revisions(s.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.render(w, r, "history.page.html", &templateData{
+		Revisions: revisions,
+		Snippet:   s,
+	})
+}
+
+// Diff between two snippet revisions GET /snippet/:id/diff?from=N&to=M
+func (app *application) snippetDiff(w http.ResponseWriter, r *http.Request) {
+	s, ok := app.snippet(w, r)
+	if !ok {
+		return
+	}
+
+	// The history reveals the content, so it needs the passphrase too
+	if app.locked(r, s) {
+		http.Redirect(w, r, snippetURL(s), http.StatusSeeOther)
+		return
+	}
+	if app.historyHidden(r, s) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var revisions [2]*models.Revision
+	for i, number := range []int{from, to} {
+		revisions[i], err = app.snippets.Revision(s.ID, number)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.notFound(w)
+			} else {
+				app.serverError(w, err)
+			}
+			return
+		}
+	}
+
+	app.render(w, r, "diff.page.html", &templateData{
+		FromRevision: revisions[0],
+		Hunks:        diff.Hunks(diff.Lines(revisions[0].Content, revisions[1].Content), diffContext),
+		Snippet:      s,
+		ToRevision:   revisions[1],
+	})
+}
+
+// Layouts of the compare page
+const (
+	layoutSplit   = "split"
+	layoutUnified = "unified"
+)
+
+// Compare two snippets GET /compare?a=ID&b=ID&layout=split|unified
+func (app *application) compareSnippets(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	// Without the snippets only the compare form is shown
+	if q.Get("a") == "" && q.Get("b") == "" {
+		app.render(w, r, "compare.page.html", &templateData{
+			Form:   forms.New(q),
+			Layout: layoutSplit,
+		})
+		return
+	}
+
+	layout := q.Get("layout")
+	if layout == "" {
+		layout = layoutSplit
+	}
+	if layout != layoutSplit && layout != layoutUnified {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var snippets [2]*models.Snippet
+	for i, param := range []string{"a", "b"} {
+		id, err := strconv.Atoi(q.Get(param))
+		if err != nil || id < 1 {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+
+		s, ok := app.snippetByID(w, r, id)
+		if !ok {
+			return
+		}
+		if app.locked(r, s) {
+			app.clientError(w, http.StatusForbidden)
+			return
+		}
+		snippets[i] = s
+	}
+
+	// Both snippets are shown, so both count as viewed
+	for i, s := range snippets {
+		s, ok := app.view(w, r, s)
+		if !ok {
+			return
+		}
+		snippets[i] = s
+	}
+
+	app.render(w, r, "compare.page.html", &templateData{
+		Form:     forms.New(q),
+		Hunks:    diff.Hunks(diff.Lines(snippets[0].Content, snippets[1].Content), diffContext),
+		Layout:   layout,
+		Snippets: snippets[:],
+	})
+}
+
 // Sign up user GET /user/signup
 func (app *application) signupUserForm(w http.ResponseWriter, r *http.Request) {
 	app.render(w, r, "signup.page.html", &templateData{
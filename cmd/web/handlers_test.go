@@ -1,10 +1,16 @@
 package main
 
 import (
+	"archive/zip"
 	"bytes"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
 
 	"net/http"
 	"testing"
+	"time"
 )
 
 type EmptyHandler http.Handler
@@ -103,6 +109,9 @@ func TestHome(t *testing.T) {
 		{
 			desc: "Valid", urlPath: "/", err: false, wantCode: http.StatusOK, wantBody: []byte(`<th>Title</th>`),
 		},
+		{
+			desc: "Tag cloud", urlPath: "/", err: false, wantCode: http.StatusOK, wantBody: []byte(`<a class='tag weight-5' href='/tag/haiku'`),
+		},
 		{
 			desc: "Latest() ERR", urlPath: "/", err: true, wantCode: http.StatusInternalServerError, wantBody: nil,
 		},
@@ -130,6 +139,107 @@ func TestHome(t *testing.T) {
 	}
 }
 
+// archive() GET /snippets
+func TestArchive(t *testing.T) {
+	app := newTestApplication(t, false)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody []byte
+	}{
+		{"First page", "/snippets", http.StatusOK, []byte(`<a class='next' href='/snippets?page=2'>`)},
+		{"Last page", "/snippets?page=2", http.StatusOK, []byte(`<a class='prev' href='/snippets?page=1'>`)},
+		{"Page after last", "/snippets?page=3", http.StatusNotFound, nil},
+		{"Zero page", "/snippets?page=0", http.StatusNotFound, nil},
+		{"String page", "/snippets?page=foo", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+// search() GET /search
+func TestSearch(t *testing.T) {
+	app := newTestApplication(t, false)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody []byte
+	}{
+		{"Empty query", "/search", http.StatusOK, []byte(`<input type="submit" value="Search">`)},
+		{"Match", "/search?q=pond", http.StatusOK, []byte(`silent <mark>pond</mark>`)},
+		{"No match", "/search?q=frog", http.StatusOK, []byte("Nothing was found")},
+		{"Protected snippet", "/search?q=dew", http.StatusOK, []byte("Nothing was found")},
+		{"View-limited snippet", "/search?q=crow", http.StatusOK, []byte("Nothing was found")},
+		{"String page", "/search?q=pond&page=foo", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+// showTag() GET /tag/:name
+func TestShowTag(t *testing.T) {
+	app := newTestApplication(t, false)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody []byte
+	}{
+		{"Valid tag", "/tag/haiku", http.StatusOK, []byte("An old silent pond")},
+		{"Unknown tag", "/tag/prose", http.StatusNotFound, nil},
+		{"Page after last", "/tag/haiku?page=2", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
 // about() GET /about
 func TestAbout(t *testing.T) {
 	// Create a new instance of our application struct which uses the mocked
@@ -208,6 +318,8 @@ func TestCreateSnippetForm(t *testing.T) {
 	// Establish a new test server for running end-to-end tests.
 	ts := newTestServer(t, app.routes())
 	defer ts.Close()
+	// Log in the mock user, creating snippets requires authentication
+	ts.login(t)
 
 	testCases := []struct {
 		desc     string
@@ -236,7 +348,231 @@ func TestCreateSnippetForm(t *testing.T) {
 	}
 }
 
-//TODO createSnippet() POST /snippet/create
+// createSnippet() POST /snippet/create
+func TestCreateSnippet(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/snippet/create")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name       string
+		title      string
+		language   string
+		tags       string
+		visibility string
+		wantCode   int
+		wantBody   []byte
+	}{
+		{"Valid submission", "Title", "go", "go, sql", "public", http.StatusSeeOther, nil},
+		{"No tags", "Title", "plaintext", "", "public", http.StatusSeeOther, nil},
+		{"Unlisted", "Title", "go", "", "unlisted", http.StatusSeeOther, nil},
+		{"Empty title", "", "go", "", "public", http.StatusOK, []byte("This field cannot be blank")},
+		{"Unknown language", "Title", "cobol", "", "public", http.StatusOK, []byte("This field is invalid")},
+		{"Unknown visibility", "Title", "go", "", "secret", http.StatusOK, []byte("This field is invalid")},
+		{"Invalid tag", "Title", "go", "go, my tag", "public", http.StatusOK, []byte("is invalid")},
+		{"Too many tags", "Title", "go", "a,b,c,d,e,f,g,h,i,j,k", "public", http.StatusOK, []byte("Too many tags")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", tt.title)
+			form.Add("content", "Content")
+			form.Add("language", tt.language)
+			form.Add("tags", tt.tags)
+			form.Add("visibility", tt.visibility)
+			form.Add("expiry", "duration")
+			form.Add("expires_in", "7")
+			form.Add("expires_unit", "days")
+			form.Add("csrf_token", csrfToken)
+
+			code, _, body := ts.postForm(t, "/snippet/create", form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+// createSnippet() POST /snippet/create with a limit of views
+func TestCreateSnippetMaxViews(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/snippet/create")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name     string
+		maxViews string
+		wantCode int
+		wantBody []byte
+	}{
+		{"Unlimited", "", http.StatusSeeOther, nil},
+		{"Burn after reading", "1", http.StatusSeeOther, nil},
+		{"Many views", "1000", http.StatusSeeOther, nil},
+		{"Zero", "0", http.StatusOK, []byte("This field must be between 1 and 1000")},
+		{"Not a number", "ten", http.StatusOK, []byte("This field must be a number")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", "Title")
+			form.Add("content", "Content")
+			form.Add("language", "plaintext")
+			form.Add("visibility", "public")
+			form.Add("max_views", tt.maxViews)
+			form.Add("expiry", "duration")
+			form.Add("expires_in", "7")
+			form.Add("expires_unit", "days")
+			form.Add("csrf_token", csrfToken)
+
+			code, _, body := ts.postForm(t, "/snippet/create", form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+// createSnippet() POST /snippet/create with files
+func TestCreateSnippetFiles(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/snippet/create")
+	csrfToken := extractCSRFToken(t, body)
+
+	type file struct{ name, language, content string }
+
+	many := make([]file, maxFiles+1)
+	for i := range many {
+		many[i] = file{fmt.Sprintf("file%d.txt", i), "plaintext", "content"}
+	}
+
+	tests := []struct {
+		name     string
+		files    []file
+		wantCode int
+		wantBody []byte
+	}{
+		{"Two files", []file{{"Dockerfile", "docker", "FROM alpine"}, {"entrypoint.sh", "bash", "echo"}}, http.StatusSeeOther, nil},
+		{"Blank entry", []file{{"Dockerfile", "docker", "FROM alpine"}, {"", "plaintext", ""}}, http.StatusSeeOther, nil},
+		{"No content", []file{{"Dockerfile", "docker", ""}}, http.StatusOK, []byte("This field cannot be blank")},
+		{"No name", []file{{"", "docker", "FROM alpine"}}, http.StatusOK, []byte("This field cannot be blank")},
+		{"Path", []file{{"../etc/passwd", "plaintext", "root"}}, http.StatusOK, []byte("This field is invalid")},
+		{"Unknown language", []file{{"main.cob", "cobol", "STOP RUN"}}, http.StatusOK, []byte("This field is invalid")},
+		{"Same name", []file{{"a.sh", "bash", "echo"}, {"a.sh", "bash", "echo"}}, http.StatusOK, []byte("is used twice")},
+		{"Too many files", many, http.StatusOK, []byte("Too many files")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", "Title")
+			form.Add("content", "Content")
+			form.Add("language", "plaintext")
+			form.Add("visibility", "public")
+			form.Add("expiry", "never")
+			form.Add("csrf_token", csrfToken)
+			for _, f := range tt.files {
+				form.Add("file_name", f.name)
+				form.Add("file_language", f.language)
+				form.Add("file_content", f.content)
+			}
+
+			code, _, body := ts.postForm(t, "/snippet/create", form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+// createSnippet() POST /snippet/create with the expiry policies
+func TestCreateSnippetExpiry(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/snippet/create")
+	csrfToken := extractCSRFToken(t, body)
+
+	tomorrow := time.Now().UTC().Add(24 * time.Hour).Format(expiryDateLayout)
+	yesterday := time.Now().UTC().Add(-24 * time.Hour).Format(expiryDateLayout)
+
+	tests := []struct {
+		name     string
+		expiry   string
+		in       string
+		unit     string
+		at       string
+		wantCode int
+		wantBody []byte
+	}{
+		{"One hour", "duration", "1", "hours", "", http.StatusSeeOther, nil},
+		{"Ten minutes", "duration", "10", "minutes", "", http.StatusSeeOther, nil},
+		{"Date", "date", "", "", tomorrow, http.StatusSeeOther, nil},
+		{"Never", "never", "", "", "", http.StatusSeeOther, nil},
+		{"No policy", "", "", "", "", http.StatusOK, []byte("This field cannot be blank")},
+		{"Unknown policy", "soon", "", "", "", http.StatusOK, []byte("This field is invalid")},
+		{"Unknown unit", "duration", "1", "weeks", "", http.StatusOK, []byte("This field is invalid")},
+		{"Too long", "duration", "366", "days", "", http.StatusOK, []byte("The snippet must expire in 1 minute to 365 days")},
+		{"Overflowing duration", "duration", "213504", "days", "", http.StatusOK, []byte("The snippet must expire in 1 minute to 365 days")},
+		{"Past date", "date", "", "", yesterday, http.StatusOK, []byte("The snippet must expire in 1 minute to 365 days")},
+		{"Malformed date", "date", "", "", "tomorrow", http.StatusOK, []byte("This field is invalid")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", "Title")
+			form.Add("content", "Content")
+			form.Add("language", "plaintext")
+			form.Add("visibility", "public")
+			form.Add("expiry", tt.expiry)
+			form.Add("expires_in", tt.in)
+			form.Add("expires_unit", tt.unit)
+			form.Add("expires_at", tt.at)
+			form.Add("csrf_token", csrfToken)
+
+			code, _, body := ts.postForm(t, "/snippet/create", form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
 
 // showSnippet() GET /snippet/:id
 func TestShowSnippet(t *testing.T) {
@@ -255,7 +591,19 @@ func TestShowSnippet(t *testing.T) {
 		wantBody []byte
 	}{
 		{"Valid ID", "/snippet/1", http.StatusOK, []byte("An old silent pond...")},
+		{"Author", "/snippet/1", http.StatusOK, []byte("#1 by Alex")},
+		{"Tags", "/snippet/1", http.StatusOK, []byte(`<a class='tag' href='/tag/haiku'>haiku</a>`)},
 		{"Non-existent ID", "/snippet/2", http.StatusNotFound, nil},
+		{"Unlisted ID", "/snippet/5", http.StatusNotFound, nil},
+		{"Burn after reading", "/snippet/7", http.StatusOK, []byte("This was the last view")},
+		{"Files", "/snippet/3", http.StatusOK, []byte("<strong>entrypoint.sh</strong>")},
+		{"ZIP link", "/snippet/3", http.StatusOK, []byte("/snippet/3/zip")},
+		{"Line numbers", "/snippet/1", http.StatusOK, []byte(`id="L1"><a style="outline: none; text-decoration:none; color:inherit" href="#L1">1</a>`)},
+		{"File line numbers", "/snippet/3", http.StatusOK, []byte(`id="entrypoint.sh-L1"`)},
+		{"Forked from", "/snippet/3", http.StatusOK, []byte("Forked from <a href='/snippet/1'>#1</a>")},
+		{"Forks", "/snippet/1", http.StatusOK, []byte("Forks: 1")},
+		{"Stars", "/snippet/1", http.StatusOK, []byte("Stars: 2")},
+		{"Views", "/snippet/1", http.StatusOK, []byte("Views: 3")},
 		{"Negative ID", "/snippet/-1", http.StatusNotFound, nil},
 		{"Decimal ID", "/snippet/1.23", http.StatusNotFound, nil},
 		{"String ID", "/snippet/foo", http.StatusNotFound, nil},
@@ -280,6 +628,1252 @@ func TestShowSnippet(t *testing.T) {
 
 }
 
+// showSharedSnippet() GET /s/:slug
+func TestShowSharedSnippet(t *testing.T) {
+	app := newTestApplication(t, false)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody []byte
+	}{
+		{"Valid slug", "/s/3q2-7wAAAAAAAAAAAAAAAA", http.StatusOK, []byte("Lightning flash...")},
+		{"Share link", "/s/3q2-7wAAAAAAAAAAAAAAAA", http.StatusOK, []byte("/s/3q2-7wAAAAAAAAAAAAAAAA")},
+		{"Unknown slug", "/s/foo", http.StatusNotFound, nil},
+		{"Empty slug", "/s/", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+// rawSnippet() GET /snippet/:id/raw
+func TestRawSnippet(t *testing.T) {
+	app := newTestApplication(t, false)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody []byte
+	}{
+		{"Valid ID", "/snippet/1/raw", http.StatusOK, []byte("An old silent pond...")},
+		{"Unlisted ID", "/snippet/5/raw", http.StatusNotFound, nil},
+		{"Unlisted slug", "/s/3q2-7wAAAAAAAAAAAAAAAA/raw", http.StatusOK, []byte("Lightning flash...")},
+		{"Locked", "/snippet/6/raw", http.StatusForbidden, nil},
+		{"Non-existent ID", "/snippet/2/raw", http.StatusNotFound, nil},
+		{"Unknown slug", "/s/foo/raw", http.StatusNotFound, nil},
+		{"Lines", "/snippet/3/raw?lines=2-3", http.StatusOK, []byte("winds howl in rage\nwith no leaves to blow.")},
+		{"Lines past the end", "/snippet/1/raw?lines=1-10", http.StatusOK, []byte("An old silent pond...")},
+		{"Lines out of range", "/snippet/1/raw?lines=2", http.StatusBadRequest, nil},
+		{"Invalid lines", "/snippet/1/raw?lines=foo", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if code == http.StatusOK {
+				if !bytes.Equal(body, tt.wantBody) {
+					t.Errorf("want body %q; got %q", tt.wantBody, body)
+				}
+				if ct := header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+					t.Errorf("want plain text; got %q", ct)
+				}
+				if header.Get("X-Content-Type-Options") != "nosniff" {
+					t.Error("want X-Content-Type-Options: nosniff")
+				}
+			}
+		})
+	}
+}
+
+// downloadSnippet() GET /snippet/:id/download
+func TestDownloadSnippet(t *testing.T) {
+	app := newTestApplication(t, false)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name            string
+		urlPath         string
+		wantCode        int
+		wantDisposition string
+	}{
+		{"Valid ID", "/snippet/1/download", http.StatusOK, "attachment; filename=an-old-silent-pond.txt"},
+		{"Unlisted slug", "/s/3q2-7wAAAAAAAAAAAAAAAA/download", http.StatusOK, "attachment; filename=lightning-flash.txt"},
+		{"Unlisted ID", "/snippet/5/download", http.StatusNotFound, ""},
+		{"Locked", "/snippet/6/download", http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, _ := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if cd := header.Get("Content-Disposition"); cd != tt.wantDisposition {
+				t.Errorf("want Content-Disposition %q; got %q", tt.wantDisposition, cd)
+			}
+		})
+	}
+}
+
+// zipSnippet() GET /snippet/:id/zip
+func TestZipSnippet(t *testing.T) {
+	app := newTestApplication(t, false)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name      string
+		urlPath   string
+		wantCode  int
+		wantFiles []string
+	}{
+		{"Files", "/snippet/3/zip", http.StatusOK, []string{"over-the-wintry-forest.txt", "Dockerfile", "entrypoint.sh"}},
+		{"Content only", "/snippet/1/zip", http.StatusOK, []string{"an-old-silent-pond.txt"}},
+		{"Unlisted ID", "/snippet/5/zip", http.StatusNotFound, nil},
+		{"Locked", "/snippet/6/zip", http.StatusForbidden, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+			if code != http.StatusOK {
+				return
+			}
+
+			if ct := header.Get("Content-Type"); ct != "application/zip" {
+				t.Errorf("want application/zip; got %q", ct)
+			}
+
+			zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, f := range zr.File {
+				names = append(names, f.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantFiles) {
+				t.Errorf("want files %q; got %q", tt.wantFiles, names)
+			}
+		})
+	}
+}
+
+// embedSnippet() GET /snippet/:id/embed and GET /s/:slug/embed
+func TestEmbedSnippet(t *testing.T) {
+	app := newTestApplication(t, false)
+	app.embedOrigins = []string{"https://wiki.example.com"}
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody []byte
+	}{
+		{"Valid ID", "/snippet/1", http.StatusOK, []byte("/static/js/embed.js")},
+		{"Embed", "/snippet/1/embed", http.StatusOK, []byte("An old silent pond...")},
+		{"Files", "/snippet/3/embed", http.StatusOK, []byte("<strong>entrypoint.sh</strong>")},
+		{"Unlisted slug", "/s/3q2-7wAAAAAAAAAAAAAAAA/embed", http.StatusOK, []byte("Lightning flash")},
+		{"Unlisted ID", "/snippet/5/embed", http.StatusNotFound, nil},
+		{"Locked snippet", "/snippet/6/embed", http.StatusForbidden, nil},
+		{"Non-existent ID", "/snippet/2/embed", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+
+			// Only the embed pages can be framed
+			embed := strings.HasSuffix(tt.urlPath, "/embed")
+			if xfo := header.Get("X-Frame-Options"); embed != (xfo == "") {
+				t.Errorf("want X-Frame-Options removed %v; got %q", embed, xfo)
+			}
+			csp := "frame-ancestors 'self' https://wiki.example.com"
+			if got := header.Get("Content-Security-Policy"); embed && got != csp {
+				t.Errorf("want Content-Security-Policy %q; got %q", csp, got)
+			}
+		})
+	}
+}
+
+// oembed() GET /oembed
+func TestOembed(t *testing.T) {
+	app := newTestApplication(t, false)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	snippetURL := url.QueryEscape(ts.URL + "/snippet/1")
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody []byte
+	}{
+		{"JSON", "/oembed?url=" + snippetURL, http.StatusOK, []byte(`"type":"rich"`)},
+		{"XML", "/oembed?format=xml&url=" + snippetURL, http.StatusOK, []byte("<type>rich</type>")},
+		{"Embed frame", "/oembed?url=" + snippetURL, http.StatusOK, []byte(`/snippet/1/embed\"`)},
+		{"Max size", "/oembed?maxwidth=300&maxheight=2000&url=" + snippetURL, http.StatusOK, []byte(`"width":300,"height":400`)},
+		{"Relative URL", "/oembed?url=%2Fsnippet%2F1", http.StatusOK, []byte(`"title":"An old silent pond"`)},
+		{"Invalid size", "/oembed?maxwidth=foo&url=" + snippetURL, http.StatusBadRequest, nil},
+		{"Unknown format", "/oembed?format=yaml&url=" + snippetURL, http.StatusNotImplemented, nil},
+		{"Foreign host", "/oembed?url=" + url.QueryEscape("https://example.com/snippet/1"), http.StatusNotFound, nil},
+		{"Other page", "/oembed?url=" + url.QueryEscape(ts.URL+"/snippet/1/raw"), http.StatusNotFound, nil},
+		{"Unlisted ID", "/oembed?url=" + url.QueryEscape(ts.URL+"/snippet/5"), http.StatusNotFound, nil},
+		{"Protected snippet", "/oembed?url=" + url.QueryEscape(ts.URL+"/snippet/6"), http.StatusUnauthorized, nil},
+		{"Non-existent ID", "/oembed?url=" + url.QueryEscape(ts.URL+"/snippet/2"), http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q; got %q", tt.wantBody, body)
+			}
+		})
+	}
+
+	// The snippet page links to the provider
+	_, _, body := ts.get(t, "/snippet/1")
+	if !bytes.Contains(body, []byte("application/json+oembed")) {
+		t.Error("want the oEmbed discovery link")
+	}
+}
+
+// unlockSnippet() POST /snippet/:id/unlock
+func TestUnlockSnippet(t *testing.T) {
+	app := newTestApplication(t, false)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, body := ts.get(t, "/snippet/6")
+	if code != http.StatusOK {
+		t.Errorf("want %d; got %d", http.StatusOK, code)
+	}
+	if !bytes.Contains(body, []byte("protected by a passphrase")) || bytes.Contains(body, []byte("A world of dew...")) {
+		t.Error("want the passphrase form instead of the content")
+	}
+	csrfToken := extractCSRFToken(t, body)
+
+	code, _, _ = ts.get(t, "/snippet/6/history")
+	if code != http.StatusSeeOther {
+		t.Errorf("want history of locked snippet to redirect; got %d", code)
+	}
+
+	tests := []struct {
+		name       string
+		urlPath    string
+		passphrase string
+		wantCode   int
+		wantBody   []byte
+	}{
+		{"Not protected", "/snippet/1/unlock", "", http.StatusSeeOther, nil},
+		{"Non-existent ID", "/snippet/2/unlock", "open sesame", http.StatusNotFound, nil},
+		{"Wrong passphrase", "/snippet/6/unlock", "open barley", http.StatusOK, []byte("Passphrase is incorrect")},
+		{"Right passphrase", "/snippet/6/unlock", "open sesame", http.StatusSeeOther, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("passphrase", tt.passphrase)
+			form.Add("csrf_token", csrfToken)
+
+			code, _, body := ts.postForm(t, tt.urlPath, form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+
+	// The snippet stays unlocked for the session
+	_, _, body = ts.get(t, "/snippet/6")
+	if !bytes.Contains(body, []byte("A world of dew...")) {
+		t.Error("want the content of the unlocked snippet")
+	}
+}
+
+// Wrong passphrases are rate-limited
+func TestUnlockSnippetRateLimit(t *testing.T) {
+	app := newTestApplication(t, false)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/snippet/6")
+	csrfToken := extractCSRFToken(t, body)
+
+	form := url.Values{}
+	form.Add("csrf_token", csrfToken)
+
+	for i := 0; i < unlockAttempts; i++ {
+		form.Set("passphrase", "open barley")
+		code, _, _ := ts.postForm(t, "/snippet/6/unlock", form)
+		if code != http.StatusOK {
+			t.Fatalf("attempt %d: want %d; got %d", i+1, http.StatusOK, code)
+		}
+	}
+
+	// Even the right passphrase is refused until the window is over
+	form.Set("passphrase", "open sesame")
+	code, _, _ := ts.postForm(t, "/snippet/6/unlock", form)
+	if code != http.StatusTooManyRequests {
+		t.Errorf("want %d; got %d", http.StatusTooManyRequests, code)
+	}
+}
+
+// editSnippetForm() GET /snippet/:id/edit
+func TestEditSnippetForm(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody []byte
+	}{
+		{"Own snippet", "/snippet/1/edit", http.StatusOK, []byte(`value='An old silent pond'`)},
+		{"Foreign snippet", "/snippet/3/edit", http.StatusForbidden, nil},
+		{"Non-existent ID", "/snippet/2/edit", http.StatusNotFound, nil},
+		{"String ID", "/snippet/foo/edit", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+// editSnippet() POST /snippet/:id/edit
+func TestEditSnippet(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/snippet/1/edit")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		title    string
+		wantCode int
+		wantBody []byte
+	}{
+		{"Valid submission", "/snippet/1/edit", "New title", http.StatusSeeOther, nil},
+		{"Empty title", "/snippet/1/edit", "", http.StatusOK, []byte("This field cannot be blank")},
+		{"Foreign snippet", "/snippet/3/edit", "New title", http.StatusForbidden, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", tt.title)
+			form.Add("content", "New content")
+			form.Add("language", "plaintext")
+			form.Add("visibility", "public")
+			form.Add("csrf_token", csrfToken)
+
+			code, _, body := ts.postForm(t, tt.urlPath, form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+// extendSnippet() POST /snippet/:id/extend
+func TestExtendSnippet(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/snippet/1")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		expiry   string
+		in       string
+		wantCode int
+		wantBody []byte
+	}{
+		{"Longer", "/snippet/1/extend", "duration", "30", http.StatusSeeOther, nil},
+		{"Never", "/snippet/1/extend", "never", "", http.StatusSeeOther, nil},
+		{"Too long", "/snippet/1/extend", "duration", "1000", http.StatusOK, []byte("The snippet must expire in")},
+		{"Foreign snippet", "/snippet/3/extend", "never", "", http.StatusForbidden, nil},
+		{"Non-existent ID", "/snippet/2/extend", "never", "", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("expiry", tt.expiry)
+			form.Add("expires_in", tt.in)
+			form.Add("expires_unit", "days")
+			form.Add("csrf_token", csrfToken)
+
+			code, _, body := ts.postForm(t, tt.urlPath, form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+// forkSnippet() POST /snippet/:id/fork and POST /s/:slug/fork
+func TestForkSnippet(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/snippet/3")
+	if !bytes.Contains(body, []byte("/snippet/3/fork")) {
+		t.Error("want the fork button")
+	}
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantLocation string
+	}{
+		{"Foreign snippet", "/snippet/3/fork", http.StatusSeeOther, "/snippet/2"},
+		{"Own snippet", "/snippet/1/fork", http.StatusSeeOther, "/snippet/2"},
+		{"Unlisted snippet", "/s/3q2-7wAAAAAAAAAAAAAAAA/fork", http.StatusSeeOther, "/snippet/2"},
+		{"Locked snippet", "/snippet/6/fork", http.StatusForbidden, ""},
+		{"Non-existent ID", "/snippet/2/fork", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", csrfToken)
+
+			code, header, _ := ts.postForm(t, tt.urlPath, form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if loc := header.Get("Location"); loc != tt.wantLocation {
+				t.Errorf("want location %q; got %q", tt.wantLocation, loc)
+			}
+		})
+	}
+}
+
+// starSnippet() POST /snippet/:id/star and POST /s/:slug/star
+func TestStarSnippet(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	// mockUser starred snippet 3 already
+	_, _, body := ts.get(t, "/snippet/3")
+	if !bytes.Contains(body, []byte("<button>Unstar</button>")) {
+		t.Error("want the unstar button")
+	}
+	_, _, body = ts.get(t, "/snippet/1")
+	if !bytes.Contains(body, []byte("<button>Star</button>")) {
+		t.Error("want the star button")
+	}
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		star         string
+		wantCode     int
+		wantLocation string
+	}{
+		{"Star", "/snippet/1/star", "true", http.StatusSeeOther, "/snippet/1"},
+		{"Star again", "/snippet/1/star", "true", http.StatusSeeOther, "/snippet/1"},
+		{"Unstar", "/snippet/3/star", "false", http.StatusSeeOther, "/snippet/3"},
+		{"Unlisted snippet", "/s/3q2-7wAAAAAAAAAAAAAAAA/star", "true", http.StatusSeeOther, "/s/3q2-7wAAAAAAAAAAAAAAAA"},
+		{"Invalid state", "/snippet/1/star", "maybe", http.StatusBadRequest, ""},
+		{"Non-existent ID", "/snippet/2/star", "true", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("star", tt.star)
+			form.Add("csrf_token", csrfToken)
+
+			code, header, _ := ts.postForm(t, tt.urlPath, form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if loc := header.Get("Location"); loc != tt.wantLocation {
+				t.Errorf("want location %q; got %q", tt.wantLocation, loc)
+			}
+		})
+	}
+}
+
+// commentSnippet() POST /snippet/:id/comment and POST /s/:slug/comment
+func TestCommentSnippet(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/snippet/1")
+	for _, want := range []string{"A frog jumps in", "Plop!", "id='comment-2'", "/comment/1/edit"} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("want body to contain %q", want)
+		}
+	}
+	// Only the comment author can change it
+	if bytes.Contains(body, []byte("/comment/2/edit")) {
+		t.Error("want no edit link for the comment of another user")
+	}
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		parentID     string
+		content      string
+		wantCode     int
+		wantLocation string
+		wantBody     []byte
+	}{
+		{"Valid comment", "/snippet/1/comment", "", "Splash!", http.StatusSeeOther, "/snippet/1#comment-3", nil},
+		{"Valid reply", "/snippet/1/comment", "1", "Splash!", http.StatusSeeOther, "/snippet/1#comment-3", nil},
+		{"Empty content", "/snippet/1/comment", "", "", http.StatusOK, "", []byte("This field cannot be blank")},
+		{"Long content", "/snippet/1/comment", "", strings.Repeat("a", 1001), http.StatusOK, "", []byte("This field is too long (maximum is 1000)")},
+		{"Reply to reply", "/snippet/1/comment", "2", "Splash!", http.StatusBadRequest, "", nil},
+		{"Invalid parent", "/snippet/1/comment", "foo", "Splash!", http.StatusBadRequest, "", nil},
+		{"Locked snippet", "/snippet/6/comment", "", "Splash!", http.StatusForbidden, "", nil},
+		{"Non-existent ID", "/snippet/2/comment", "", "Splash!", http.StatusNotFound, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("parent_id", tt.parentID)
+			form.Add("content", tt.content)
+			form.Add("csrf_token", csrfToken)
+
+			code, header, body := ts.postForm(t, tt.urlPath, form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if loc := header.Get("Location"); loc != tt.wantLocation {
+				t.Errorf("want location %q; got %q", tt.wantLocation, loc)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+// Showing the invalid comment form with the snippet counts a view, so the
+// limit of views can't be bypassed by posting empty comments
+func TestCommentLimitedSnippet(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/user/snippets")
+	form := url.Values{}
+	form.Add("content", "")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, body := ts.postForm(t, "/snippet/7/comment", form)
+	if code != http.StatusOK {
+		t.Fatalf("want %d; got %d", http.StatusOK, code)
+	}
+	for _, want := range []string{"This field cannot be blank", "This was the last view"} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("want body to contain %q", want)
+		}
+	}
+	if n := sumViews(app.views.Take(), 7); n != 1 {
+		t.Errorf("want 1 view of snippet 7; got %d", n)
+	}
+}
+
+// editCommentForm() GET /comment/:id/edit
+func TestEditCommentForm(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody []byte
+	}{
+		{"Own comment", "/comment/1/edit", http.StatusOK, []byte("A frog jumps in")},
+		{"Foreign comment", "/comment/2/edit", http.StatusForbidden, nil},
+		{"Non-existent ID", "/comment/3/edit", http.StatusNotFound, nil},
+		{"String ID", "/comment/foo/edit", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+// editComment() POST /comment/:id/edit and deleteComment() POST /comment/:id/delete
+func TestEditComment(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/comment/1/edit")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		content      string
+		wantCode     int
+		wantLocation string
+		wantBody     []byte
+	}{
+		{"Valid edit", "/comment/1/edit", "A frog jumps out", http.StatusSeeOther, "/snippet/1#comment-1", nil},
+		{"Empty content", "/comment/1/edit", "", http.StatusOK, "", []byte("This field cannot be blank")},
+		{"Foreign comment", "/comment/2/edit", "A frog jumps out", http.StatusForbidden, "", nil},
+		{"Delete", "/comment/1/delete", "", http.StatusSeeOther, "/snippet/1#comments", nil},
+		{"Delete foreign comment", "/comment/2/delete", "", http.StatusForbidden, "", nil},
+		{"Delete non-existent ID", "/comment/3/delete", "", http.StatusNotFound, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("content", tt.content)
+			form.Add("csrf_token", csrfToken)
+
+			code, header, body := ts.postForm(t, tt.urlPath, form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if loc := header.Get("Location"); loc != tt.wantLocation {
+				t.Errorf("want location %q; got %q", tt.wantLocation, loc)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+// deleteSnippet() POST /snippet/:id/delete
+func TestDeleteSnippet(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/snippet/1")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name      string
+		urlPath   string
+		csrfToken string
+		wantCode  int
+	}{
+		{"Own snippet", "/snippet/1/delete", csrfToken, http.StatusSeeOther},
+		{"Foreign snippet", "/snippet/3/delete", csrfToken, http.StatusForbidden},
+		{"Non-existent ID", "/snippet/2/delete", csrfToken, http.StatusNotFound},
+		{"Missing CSRF token", "/snippet/1/delete", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", tt.csrfToken)
+
+			code, _, _ := ts.postForm(t, tt.urlPath, form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+		})
+	}
+}
+
+// restoreSnippet() POST /snippet/:id/restore
+func TestRestoreSnippet(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/user/trash")
+	csrfToken := extractCSRFToken(t, body)
+
+	if !bytes.Contains(body, []byte("First autumn morning")) {
+		t.Errorf("want trash to contain %q", "First autumn morning")
+	}
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+	}{
+		{"Trashed snippet", "/snippet/4/restore", http.StatusSeeOther},
+		{"Not trashed snippet", "/snippet/1/restore", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", csrfToken)
+
+			code, _, _ := ts.postForm(t, tt.urlPath, form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+		})
+	}
+}
+
+// userSnippets() GET /user/snippets
+func TestUserSnippets(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Anonymous users are redirected to the login page
+	code, header, _ := ts.get(t, "/user/snippets")
+	if code != http.StatusFound {
+		t.Errorf("want %d, got %d", http.StatusFound, code)
+	}
+	if loc := header.Get("Location"); loc != "/user/login" {
+		t.Errorf("want redirect to %q, got %q", "/user/login", loc)
+	}
+
+	ts.login(t)
+
+	code, _, body := ts.get(t, "/user/snippets")
+	if code != http.StatusOK {
+		t.Errorf("want %d, got %d", http.StatusOK, code)
+	}
+	if !bytes.Contains(body, []byte("An old silent pond")) {
+		t.Errorf("want body to contain %q", "An old silent pond")
+	}
+}
+
+// userStarred() GET /user/starred
+func TestUserStarred(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Anonymous users are redirected to the login page
+	code, header, _ := ts.get(t, "/user/starred")
+	if code != http.StatusFound {
+		t.Errorf("want %d, got %d", http.StatusFound, code)
+	}
+	if loc := header.Get("Location"); loc != "/user/login" {
+		t.Errorf("want redirect to %q, got %q", "/user/login", loc)
+	}
+
+	ts.login(t)
+
+	code, _, body := ts.get(t, "/user/starred")
+	if code != http.StatusOK {
+		t.Errorf("want %d, got %d", http.StatusOK, code)
+	}
+	if !bytes.Contains(body, []byte("Over the wintry forest")) {
+		t.Errorf("want body to contain %q", "Over the wintry forest")
+	}
+	// Unlisted snippets of other users don't give away their slug
+	if bytes.Contains(body, []byte("Lightning flash")) || bytes.Contains(body, []byte("/s/")) {
+		t.Error("want the unlisted snippet of another user hidden")
+	}
+}
+
+// snippetHistory() GET /snippet/:id/history
+func TestSnippetHistory(t *testing.T) {
+	app := newTestApplication(t, false)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody []byte
+	}{
+		{"Valid ID", "/snippet/1/history", http.StatusOK, []byte("#1 An old pond")},
+		{"Non-existent ID", "/snippet/2/history", http.StatusNotFound, nil},
+		{"Limited views", "/snippet/7/history", http.StatusForbidden, nil},
+		{"String ID", "/snippet/foo/history", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+// snippetDiff() GET /snippet/:id/diff
+func TestSnippetDiff(t *testing.T) {
+	app := newTestApplication(t, false)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody []byte
+	}{
+		{"Valid revisions", "/snippet/1/diff?from=1&to=2", http.StatusOK, []byte("<tr class='insert'>")},
+		{"Same revision", "/snippet/1/diff?from=2&to=2", http.StatusOK, []byte("There are no differences")},
+		{"Non-existent revision", "/snippet/1/diff?from=1&to=3", http.StatusNotFound, nil},
+		{"Missing revision", "/snippet/1/diff?from=1", http.StatusBadRequest, nil},
+		{"Non-existent ID", "/snippet/2/diff?from=1&to=2", http.StatusNotFound, nil},
+		{"Limited views", "/snippet/7/diff?from=1&to=2", http.StatusForbidden, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+// compareSnippets() GET /compare
+func TestCompareSnippets(t *testing.T) {
+	app := newTestApplication(t, false)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody []byte
+	}{
+		{"Form only", "/compare", http.StatusOK, []byte("<form action='/compare'")},
+		{"Side by side", "/compare?a=1&b=3", http.StatusOK, []byte("<td class='insert'><pre>winds howl in rage</pre></td>")},
+		{"Unified", "/compare?a=1&b=3&layout=unified", http.StatusOK, []byte("<tr class='delete'>")},
+		{"Same snippet", "/compare?a=3&b=3", http.StatusOK, []byte("There are no differences")},
+		{"Unknown layout", "/compare?a=1&b=3&layout=diagonal", http.StatusBadRequest, nil},
+		{"Missing ID", "/compare?a=1", http.StatusBadRequest, nil},
+		{"Invalid ID", "/compare?a=1&b=foo", http.StatusBadRequest, nil},
+		{"Non-existent ID", "/compare?a=1&b=2", http.StatusNotFound, nil},
+		{"Unlisted snippet", "/compare?a=5&b=1", http.StatusNotFound, nil},
+		{"Protected snippet", "/compare?a=1&b=6", http.StatusForbidden, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+// showCollection() GET /c/:id
+func TestShowCollection(t *testing.T) {
+	app := newTestApplication(t, false)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody []byte
+	}{
+		{"Valid ID", "/c/1", http.StatusOK, []byte("Poems about ponds and winds")},
+		{"Empty collection", "/c/2", http.StatusOK, []byte("There are no snippets in this collection yet")},
+		{"Non-existent ID", "/c/9", http.StatusNotFound, nil},
+		{"String ID", "/c/foo", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+
+	// The snippets are listed in their order, unlisted ones only for their
+	// authors
+	_, _, body := ts.get(t, "/c/1")
+	first := bytes.Index(body, []byte("Over the wintry forest"))
+	second := bytes.Index(body, []byte("An old silent pond"))
+	if first < 0 || second < first {
+		t.Error("want snippet 3 listed before snippet 1")
+	}
+	if bytes.Contains(body, []byte("Lightning flash")) {
+		t.Error("want the unlisted snippet hidden")
+	}
+}
+
+// userCollections() GET /user/collections
+func TestUserCollections(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Anonymous users are redirected to the login page
+	code, header, _ := ts.get(t, "/user/collections")
+	if code != http.StatusFound {
+		t.Errorf("want %d, got %d", http.StatusFound, code)
+	}
+	if loc := header.Get("Location"); loc != "/user/login" {
+		t.Errorf("want redirect to %q, got %q", "/user/login", loc)
+	}
+
+	ts.login(t)
+
+	code, _, body := ts.get(t, "/user/collections")
+	if code != http.StatusOK {
+		t.Errorf("want %d, got %d", http.StatusOK, code)
+	}
+	if !bytes.Contains(body, []byte("<a href='/c/1'>Haiku</a>")) {
+		t.Errorf("want body to contain %q", "<a href='/c/1'>Haiku</a>")
+	}
+}
+
+// createCollection() POST /collection/create
+func TestCreateCollection(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/collection/create")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		title        string
+		wantCode     int
+		wantLocation string
+		wantBody     []byte
+	}{
+		{"Valid collection", "Seasons", http.StatusSeeOther, "/c/3", nil},
+		{"Empty title", "", http.StatusOK, "", []byte("This field cannot be blank")},
+		{"Long title", strings.Repeat("a", 101), http.StatusOK, "", []byte("This field is too long")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", tt.title)
+			form.Add("csrf_token", csrfToken)
+
+			code, header, body := ts.postForm(t, "/collection/create", form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if loc := header.Get("Location"); loc != tt.wantLocation {
+				t.Errorf("want location %q; got %q", tt.wantLocation, loc)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+// editCollection() POST /collection/:id/edit
+func TestEditCollection(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	code, _, body := ts.get(t, "/collection/1/edit")
+	if code != http.StatusOK {
+		t.Fatalf("want %d; got %d", http.StatusOK, code)
+	}
+	// The owner sees every snippet of the collection to manage it
+	for _, want := range []string{"value='Haiku'", "Lightning flash (unlisted)", "<button>Remove</button>"} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("want body to contain %q", want)
+		}
+	}
+	// The unlisted snippet of another user isn't linked by its slug
+	if bytes.Contains(body, []byte("/s/")) {
+		t.Error("want no link to the unlisted snippet of another user")
+	}
+	csrfToken := extractCSRFToken(t, body)
+
+	code, _, _ = ts.get(t, "/collection/2/edit")
+	if code != http.StatusForbidden {
+		t.Errorf("foreign collection: want %d; got %d", http.StatusForbidden, code)
+	}
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		title        string
+		wantCode     int
+		wantLocation string
+	}{
+		{"Valid collection", "/collection/1/edit", "Haiku by Basho", http.StatusSeeOther, "/c/1"},
+		{"Empty title", "/collection/1/edit", "", http.StatusOK, ""},
+		{"Foreign collection", "/collection/2/edit", "Mine now", http.StatusForbidden, ""},
+		{"Non-existent ID", "/collection/9/edit", "Haiku", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", tt.title)
+			form.Add("csrf_token", csrfToken)
+
+			code, header, _ := ts.postForm(t, tt.urlPath, form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if loc := header.Get("Location"); loc != tt.wantLocation {
+				t.Errorf("want location %q; got %q", tt.wantLocation, loc)
+			}
+		})
+	}
+}
+
+// deleteCollection(), moveCollectionSnippet() and removeCollectionSnippet()
+// POST /collection/:id/delete, /move and /remove
+func TestManageCollection(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/collection/1/edit")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		snippet      string
+		direction    string
+		wantCode     int
+		wantLocation string
+	}{
+		{"Move up", "/collection/1/move", "1", "up", http.StatusSeeOther, "/collection/1/edit"},
+		{"Move down", "/collection/1/move", "1", "down", http.StatusSeeOther, "/collection/1/edit"},
+		{"Invalid direction", "/collection/1/move", "1", "left", http.StatusBadRequest, ""},
+		{"Snippet not in collection", "/collection/1/move", "7", "up", http.StatusNotFound, ""},
+		{"Remove", "/collection/1/remove", "3", "", http.StatusSeeOther, "/collection/1/edit"},
+		{"Invalid snippet", "/collection/1/remove", "foo", "", http.StatusBadRequest, ""},
+		{"Foreign collection", "/collection/2/remove", "3", "", http.StatusForbidden, ""},
+		{"Delete", "/collection/1/delete", "", "", http.StatusSeeOther, "/user/collections"},
+		{"Delete foreign collection", "/collection/2/delete", "", "", http.StatusForbidden, ""},
+		{"Delete non-existent ID", "/collection/9/delete", "", "", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("snippet", tt.snippet)
+			form.Add("direction", tt.direction)
+			form.Add("csrf_token", csrfToken)
+
+			code, header, _ := ts.postForm(t, tt.urlPath, form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if loc := header.Get("Location"); loc != tt.wantLocation {
+				t.Errorf("want location %q; got %q", tt.wantLocation, loc)
+			}
+		})
+	}
+}
+
+// collectSnippet() POST /snippet/:id/collect and POST /s/:slug/collect
+func TestCollectSnippet(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	_, _, body := ts.get(t, "/snippet/3")
+	if !bytes.Contains(body, []byte("<option value='1'>Haiku</option>")) {
+		t.Error("want the collections of the user to choose from")
+	}
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		collection   string
+		wantCode     int
+		wantLocation string
+	}{
+		{"Valid collection", "/snippet/3/collect", "1", http.StatusSeeOther, "/snippet/3"},
+		{"Unlisted snippet", "/s/3q2-7wAAAAAAAAAAAAAAAA/collect", "1", http.StatusSeeOther, "/s/3q2-7wAAAAAAAAAAAAAAAA"},
+		{"Foreign collection", "/snippet/3/collect", "2", http.StatusForbidden, ""},
+		{"Non-existent collection", "/snippet/3/collect", "9", http.StatusNotFound, ""},
+		{"Invalid collection", "/snippet/3/collect", "foo", http.StatusBadRequest, ""},
+		{"Non-existent snippet", "/snippet/2/collect", "1", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("collection", tt.collection)
+			form.Add("csrf_token", csrfToken)
+
+			code, header, _ := ts.postForm(t, tt.urlPath, form)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if loc := header.Get("Location"); loc != tt.wantLocation {
+				t.Errorf("want location %q; got %q", tt.wantLocation, loc)
+			}
+		})
+	}
+}
+
 //TODO signupUserForm() GET /user/signup
 
 // signupUser() POST /user/signup
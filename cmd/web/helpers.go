@@ -2,11 +2,14 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"net/http"
 	"runtime/debug"
+	"strconv"
 	"time"
 
+	"github.com/alekslesik/snippetbox.learn/pkg/forms"
 	"github.com/alekslesik/snippetbox.learn/pkg/models"
 	"github.com/justinas/nosurf"
 )
@@ -70,13 +73,27 @@ func (app *application) addDefaultData(td *templateData, r *http.Request) *templ
 	td.Flash = app.session.PopString(r, "flash")
 	// Check if user is authenticate.
 	td.AuthenticatedUser = app.authenticatedUser(r)
+	// Add the URL of the site for links used elsewhere, e.g. embed codes.
+	td.BaseURL = baseURL(r)
 	// Add the CSRF token to the templateData struct.
 	td.CSRFToken = nosurf.Token(r)
+	// Add languages supported by the snippet forms.
+	td.Languages = languages
 	//
 
 	return td
 }
 
+// Return the scheme and host the request was sent to
+func baseURL(r *http.Request) string {
+	scheme := "https"
+	if r.TLS == nil {
+		scheme = "http"
+	}
+
+	return scheme + "://" + r.Host
+}
+
 // Return userID ID from session
 func (app *application) authenticatedUser(r *http.Request) *models.User {
 	user, ok := r.Context().Value(contextKeyUser).(*models.User)
@@ -85,3 +102,350 @@ func (app *application) authenticatedUser(r *http.Request) *models.User {
 	}
 	return user
 }
+
+// Most files a snippet can have besides its content
+const maxFiles = 10
+
+// Repeated fields of the file entries of the snippet forms
+var fileFields = []string{"file_name", "file_language", "file_content"}
+
+// Check the file entries of the snippet forms. Entries without a name and
+// content are left out.
+func validateFiles(form *forms.Form) {
+	n := 0
+	for i := 0; i < form.Count(fileFields...); i++ {
+		if form.BlankAt(i, "file_name", "file_content") {
+			continue
+		}
+		n++
+
+		form.RequiredAt(i, "file_name", "file_content")
+		form.MaxLengthAt("file_name", i, 100)
+		form.MatchesPatternAt("file_name", i, forms.FilenameRX)
+		form.PermittedValuesAt("file_language", i, languageNames()...)
+	}
+	form.DistinctAt("file_name")
+
+	if n > maxFiles {
+		form.Errors.Add("files", fmt.Sprintf("Too many files (maximum is %d)", maxFiles))
+	}
+}
+
+// Return the files of the valid snippet form
+func formFiles(form *forms.Form) []*models.File {
+	var files []*models.File
+	for i := 0; i < form.Count(fileFields...); i++ {
+		if form.BlankAt(i, "file_name", "file_content") {
+			continue
+		}
+		files = append(files, &models.File{
+			Name:     form.GetAt("file_name", i),
+			Language: form.GetAt("file_language", i),
+			Content:  form.GetAt("file_content", i),
+		})
+	}
+	return files
+}
+
+// Check the title, content, language, tags, visibility and files fields
+// shared by the create and edit snippet forms, and the passphrase and limit
+// of views which only new snippets get. The expiry is checked by
+// validateExpiry.
+func validateSnippetForm(form *forms.Form) {
+	form.Required("title", "content", "language", "visibility")
+	form.MaxLength("title", 100)
+	form.PermittedValues("language", languageNames()...)
+	form.ValidTags("tags", 10, 30)
+	form.PermittedValues("visibility", models.Public, models.Unlisted)
+	form.MaxLength("passphrase", 72)
+	form.IntRange("max_views", 1, 1000)
+	validateFiles(form)
+}
+
+// Longest comment in characters
+const maxCommentLength = 1000
+
+// Check the content field of the comment forms
+func validateCommentForm(form *forms.Form) {
+	form.Required("content")
+	form.MaxLength("content", maxCommentLength)
+}
+
+// The snippet helper fetches the snippet from the :id URL parameter. If
+// there is no such snippet, the relevant error response is sent and false
+// is returned. Unlisted snippets are found by ID only for their authors.
+func (app *application) snippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	id, err := strconv.Atoi(r.URL.Query().Get(":id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return nil, false
+	}
+
+	return app.snippetByID(w, r, id)
+}
+
+// Fetch the snippet by ID like the snippet helper does
+func (app *application) snippetByID(w http.ResponseWriter, r *http.Request, id int) (*models.Snippet, bool) {
+	// Looking the snippet up doesn't count as a view, see renderSnippet
+	s, err := app.snippets.Peek(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	if s.Unlisted() && !app.isAuthor(r, s) {
+		app.notFound(w)
+		return nil, false
+	}
+
+	return s, true
+}
+
+// The sharedSnippet helper fetches the unlisted snippet from the :slug URL
+// parameter. If there is no such snippet, the relevant error response is
+// sent and false is returned.
+func (app *application) sharedSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	s, err := app.snippets.GetBySlug(r.URL.Query().Get(":slug"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return s, true
+}
+
+// Fetch the snippet from the :slug URL parameter if the route has one or
+// from the :id URL parameter otherwise
+func (app *application) requestedSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	if r.URL.Query().Get(":slug") != "" {
+		return app.sharedSnippet(w, r)
+	}
+
+	return app.snippet(w, r)
+}
+
+// Return the URL the snippet is shown at
+func snippetURL(s *models.Snippet) string {
+	if s.Unlisted() {
+		return "/s/" + s.Slug
+	}
+
+	return fmt.Sprintf("/snippet/%d", s.ID)
+}
+
+// Return the session key which marks the snippet as unlocked
+func unlockedKey(id int) string {
+	return fmt.Sprintf("unlocked:%d", id)
+}
+
+// Return true if the content of the snippet must be hidden behind the
+// passphrase form. Authors and sessions which unlocked the snippet before
+// see it straight away.
+func (app *application) locked(r *http.Request, s *models.Snippet) bool {
+	return s.Protected && !app.isAuthor(r, s) && !app.session.GetBool(r, unlockedKey(s.ID))
+}
+
+// Return true if the history of the snippet is hidden from the user. The
+// revisions hold the content without counting a view, so only the author
+// sees them for snippets with a limit of views.
+func (app *application) historyHidden(r *http.Request, s *models.Snippet) bool {
+	return s.MaxViews > 0 && !app.isAuthor(r, s)
+}
+
+// Count the view of the snippet unless it is shown to its author and return
+// the viewed snippet. The snippet may have run out of views since it was
+// looked up, then the relevant error response is sent and false is returned.
+// The view is also counted for the analytics, which are written later.
+func (app *application) view(w http.ResponseWriter, r *http.Request, s *models.Snippet) (*models.Snippet, bool) {
+	if app.isAuthor(r, s) {
+		return s, true
+	}
+
+	s, err := app.snippets.Get(s.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	app.views.Add(s.ID)
+
+	return s, true
+}
+
+// Render the snippet page or the passphrase form if the snippet is locked.
+// Showing the content counts as a view of the snippet.
+func (app *application) renderSnippet(w http.ResponseWriter, r *http.Request, s *models.Snippet) {
+	if app.locked(r, s) {
+		app.render(w, r, "unlock.page.html", &templateData{
+			Form:    forms.New(nil),
+			Snippet: s,
+		})
+		return
+	}
+
+	s, ok := app.view(w, r, s)
+	if !ok {
+		return
+	}
+
+	app.renderShow(w, r, &templateData{Snippet: s})
+}
+
+// Render the show page of td.Snippet together with its comments. Forms
+// which aren't set in td are added empty, the extend form for the author and
+// the comment forms for authenticated users.
+func (app *application) renderShow(w http.ResponseWriter, r *http.Request, td *templateData) {
+	var err error
+	s := td.Snippet
+
+	if td.Form == nil && app.isAuthor(r, s) {
+		// The author can extend the expiry from the snippet page
+		td.Form = forms.New(defaultExpiry())
+	}
+
+	if user := app.authenticatedUser(r); user != nil {
+		td.Starred, err = app.snippets.IsStarred(s.ID, user.ID)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		if td.CommentForm == nil {
+			td.CommentForm = forms.New(nil)
+		}
+
+		// The snippet can be added to any collection of the user
+		td.Collections, err = app.collections.ByUser(user.ID)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	td.Comments, err = app.comments.BySnippet(s.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.render(w, r, "show.page.html", td)
+}
+
+// Check the posted passphrase of the snippet and unlock it for the session.
+// Wrong passphrases are counted per client address and snippet, once there
+// are too many of them the client has to wait.
+func (app *application) unlock(w http.ResponseWriter, r *http.Request, s *models.Snippet) {
+	if !s.Protected {
+		http.Redirect(w, r, snippetURL(s), http.StatusSeeOther)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	key := fmt.Sprintf("%s/%d", clientIP(r), s.ID)
+	if !app.unlockLimiter.Allow(key) {
+		app.clientError(w, http.StatusTooManyRequests)
+		return
+	}
+
+	form := forms.New(r.PostForm)
+	err = app.snippets.Unlock(s.ID, form.Get("passphrase"))
+	if errors.Is(err, models.ErrInvalidCredentials) {
+		app.unlockLimiter.Fail(key)
+		form.Errors.Add("generic", "Passphrase is incorrect")
+		app.render(w, r, "unlock.page.html", &templateData{
+			Form:    form,
+			Snippet: s,
+		})
+		return
+	} else if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.session.Put(r, unlockedKey(s.ID), true)
+
+	http.Redirect(w, r, snippetURL(s), http.StatusSeeOther)
+}
+
+// The ownComment helper fetches the comment from the :id URL parameter
+// together with its snippet. Like ownSnippet, it sends the relevant error
+// response and returns false unless the authenticated user wrote the comment.
+func (app *application) ownComment(w http.ResponseWriter, r *http.Request) (*models.Comment, *models.Snippet, bool) {
+	id, err := strconv.Atoi(r.URL.Query().Get(":id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return nil, nil, false
+	}
+
+	c, err := app.comments.Get(id)
+	if errors.Is(err, models.ErrNoRecord) {
+		app.notFound(w)
+		return nil, nil, false
+	} else if err != nil {
+		app.serverError(w, err)
+		return nil, nil, false
+	}
+
+	if c.UserID != app.authenticatedUser(r).ID {
+		app.clientError(w, http.StatusForbidden)
+		return nil, nil, false
+	}
+
+	// The snippet is needed to link back to it
+	s, err := app.snippets.Peek(c.SnippetID)
+	if errors.Is(err, models.ErrNoRecord) {
+		app.notFound(w)
+		return nil, nil, false
+	} else if err != nil {
+		app.serverError(w, err)
+		return nil, nil, false
+	}
+
+	return c, s, true
+}
+
+// Return the URL of the comment on the snippet page
+func commentURL(s *models.Snippet, id int) string {
+	return fmt.Sprintf("%s#comment-%d", snippetURL(s), id)
+}
+
+// Return true if the authenticated user is the author of the snippet
+func (app *application) isAuthor(r *http.Request, s *models.Snippet) bool {
+	user := app.authenticatedUser(r)
+	return user != nil && user.ID == s.UserID
+}
+
+// The ownSnippet helper fetches the snippet from the :id URL parameter and
+// checks that it belongs to the authenticated user. If it doesn't, the
+// relevant error response is sent and false is returned.
+func (app *application) ownSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	s, ok := app.snippet(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	// Only the author is allowed to change the snippet
+	if !app.isAuthor(r, s) {
+		app.clientError(w, http.StatusForbidden)
+		return nil, false
+	}
+
+	return s, true
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+)
+
+// Programming language a snippet can be highlighted as. Ext is the file
+// extension used for downloads.
+type language struct {
+	Name  string
+	Label string
+	Ext   string
+}
+
+// Languages supported by the snippet forms. Name is the chroma lexer name,
+// except for markdown snippets, which are rendered to HTML instead.
+var languages = []language{
+	{"plaintext", "Plain text", ".txt"},
+	{"markdown", "Markdown", ".md"},
+	{"bash", "Bash", ".sh"},
+	{"c", "C", ".c"},
+	{"css", "CSS", ".css"},
+	{"docker", "Dockerfile", ".dockerfile"},
+	{"go", "Go", ".go"},
+	{"html", "HTML", ".html"},
+	{"java", "Java", ".java"},
+	{"javascript", "JavaScript", ".js"},
+	{"json", "JSON", ".json"},
+	{"python", "Python", ".py"},
+	{"sql", "SQL", ".sql"},
+	{"yaml", "YAML", ".yaml"},
+}
+
+// Return names of all supported languages
+func languageNames() []string {
+	names := make([]string, len(languages))
+	for i, l := range languages {
+		names[i] = l.Name
+	}
+	return names
+}
+
+// Return the file extension of the language, plain text for unknown ones
+func languageExt(lang string) string {
+	for _, l := range languages {
+		if l.Name == lang {
+			return l.Ext
+		}
+	}
+	return ".txt"
+}
+
+// Style and formatter used for every highlighted snippet
+var (
+	codeStyle     = styles.Get("github")
+	codeFormatter = html.New(html.TabWidth(4))
+)
+
+// Return the code highlighted as HTML for the given language. Every token is
+// HTML escaped by the formatter, so the result is safe to output as is.
+// Unknown languages are rendered as plain text.
+func highlightCode(code, lang string) template.HTML {
+	return highlightWith(codeFormatter, code, lang)
+}
+
+// Return the code highlighted like highlightCode with linkable line numbers.
+// The line numbers have the IDs prefix followed by the number, e.g. L10.
+func numberedCode(code, lang, prefix string) template.HTML {
+	formatter := html.New(html.TabWidth(4), html.WithLineNumbers(true), html.LinkableLineNumbers(true, prefix))
+	return highlightWith(formatter, code, lang)
+}
+
+func highlightWith(formatter *html.Formatter, code, lang string) template.HTML {
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	buf := new(bytes.Buffer)
+
+	iterator, err := lexer.Tokenise(nil, code)
+	if err == nil {
+		err = formatter.Format(buf, codeStyle, iterator)
+	}
+	if err != nil {
+		// Fall back to the escaped code without highlighting
+		return template.HTML("<pre><code>" + template.HTMLEscapeString(code) + "</code></pre>")
+	}
+
+	return template.HTML(buf.String())
+}
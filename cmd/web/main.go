@@ -1,14 +1,20 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/alekslesik/snippetbox.learn/pkg/models"
@@ -23,21 +29,68 @@ type contextKey string
 var contextKeyUser = contextKey("user")
 
 type application struct {
-	gopath   string
-	errorLog *log.Logger
-	infoLog  *log.Logger
-	session  *sessions.Session
-	snippets interface {
-		Insert(title, content, expires string) (int, error)
+	gopath      string
+	collections interface {
+		Insert(c *models.Collection) (int, error)
+		Get(id int) (*models.Collection, error)
+		ByUser(userID int) ([]*models.Collection, error)
+		Update(c *models.Collection) error
+		Delete(id int) error
+		Snippets(id int) ([]*models.Snippet, error)
+		Add(id, snippetID int) error
+		Remove(id, snippetID int) error
+		Move(id, snippetID int, up bool) error
+	}
+	comments interface {
+		Insert(c *models.Comment) (int, error)
+		Get(id int) (*models.Comment, error)
+		BySnippet(snippetID int) ([]*models.Comment, error)
+		Update(id int, content string) error
+		Delete(id int) error
+	}
+	embedOrigins []string
+	errorLog     *log.Logger
+	infoLog      *log.Logger
+	maxExpiry    time.Duration
+	minExpiry    time.Duration
+	session      *sessions.Session
+	snippets     interface {
+		Insert(s *models.Snippet) (int, error)
+		Update(s *models.Snippet) error
+		Extend(id int, expires time.Time) error
 		Get(id int) (*models.Snippet, error)
+		Peek(id int) (*models.Snippet, error)
+		GetBySlug(slug string) (*models.Snippet, error)
+		Unlock(id int, passphrase string) error
 		Latest() ([]*models.Snippet, error)
+		Archive(limit, offset int) ([]*models.Snippet, int, error)
+		Search(query string, limit, offset int) ([]*models.Snippet, int, error)
+		ByTag(tag string, limit, offset int) ([]*models.Snippet, int, error)
+		TagCloud() ([]*models.Tag, error)
+		ByUser(userID int) ([]*models.Snippet, error)
+		Delete(id int) error
+		Restore(id, userID int) error
+		Trash(userID int) ([]*models.Snippet, error)
+		Purge(retention time.Duration) (int, error)
+		DeleteExpired(limit int) (int, error)
+		Revisions(snippetID int) ([]*models.Revision, error)
+		Revision(snippetID, number int) (*models.Revision, error)
+		Star(id, userID int, star bool) error
+		IsStarred(id, userID int) (bool, error)
+		Starred(userID int) ([]*models.Snippet, error)
+		RecordViews(views []*models.ViewCount) error
+		DailyViews(id int, since time.Time) ([]*models.ViewCount, error)
 	}
-	templateCache map[string]*template.Template
-	users         interface {
+	reapBatch      int
+	templateCache  map[string]*template.Template
+	trashRetention time.Duration
+	unlockLimiter  *rateLimiter
+	users          interface {
 		Insert(name, email, password string) error
 		Authenticate(email, password string) (int, error)
 		Get(id int) (*models.User, error)
 	}
+	views *viewCounter
 }
 
 func main() {
@@ -45,6 +98,14 @@ func main() {
 	addr := flag.String("addr", ":4000", "Сетевой адрес веб-сервера")
 	dsn := flag.String("dsn", "web:ndJMv9zrJw@/snippetbox?parseTime=true", "Название MySQL источника данных")
 	secret := flag.String("secret", "s6Ndh+pPbnzHbS*+9Pk8qGWhTzbpa@ge", "Secret")
+	minExpiry := flag.Duration("min-expiry", time.Minute, "Shortest time a snippet can expire in")
+	maxExpiry := flag.Duration("max-expiry", 365*24*time.Hour, "Longest time a snippet can expire in, unless it never expires")
+	trashRetention := flag.Duration("trash-retention", 30*24*time.Hour, "How long deleted snippets are kept in the trash")
+	reapInterval := flag.Duration("reap-interval", 10*time.Minute, "How often expired snippets are deleted")
+	reapBatch := flag.Int("reap-batch", 1000, "Maximum number of expired snippets deleted at once")
+	reapOnce := flag.Bool("reap", false, "Delete expired snippets and exit, e.g. from cron")
+	embedOrigins := flag.String("embed-origins", "", "Space separated origins allowed to frame embedded snippets, e.g. https://wiki.example.com")
+	viewsFlush := flag.Duration("views-flush", time.Minute, "How often counted snippet views are written to the database")
 	flag.Parse()
 
 	// Go path
@@ -65,6 +126,23 @@ func main() {
 	// infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
 	errorLog := log.New(f, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
 
+	// A batch of 0 never finishes reaping and tickers panic on intervals <= 0
+	if *reapBatch <= 0 {
+		errorLog.Fatal("-reap-batch must be positive")
+	}
+	if *reapInterval <= 0 {
+		errorLog.Fatal("-reap-interval must be positive")
+	}
+	if *viewsFlush <= 0 {
+		errorLog.Fatal("-views-flush must be positive")
+	}
+	// Origins go straight into the CSP header of embedded snippets
+	for _, origin := range strings.Fields(*embedOrigins) {
+		if !validOrigin(origin) {
+			errorLog.Fatalf("-embed-origins: %q is not an origin like https://wiki.example.com", origin)
+		}
+	}
+
 	// Open DB connection pull
 	db, err := openDB(*dsn)
 	if err != nil {
@@ -86,15 +164,46 @@ func main() {
 
 	// Initialisation application struct
 	app := &application{
-		gopath:        gopath,
-		errorLog:      errorLog,
-		infoLog:       infoLog,
-		session:       session,
-		snippets:      &mysql.SnippetModel{DB: db},
-		templateCache: templateCache,
-		users:         &mysql.UserModel{DB: db},
+		gopath:         gopath,
+		collections:    &mysql.CollectionModel{DB: db},
+		comments:       &mysql.CommentModel{DB: db},
+		embedOrigins:   strings.Fields(*embedOrigins),
+		errorLog:       errorLog,
+		infoLog:        infoLog,
+		maxExpiry:      *maxExpiry,
+		minExpiry:      *minExpiry,
+		reapBatch:      *reapBatch,
+		session:        session,
+		snippets:       &mysql.SnippetModel{DB: db},
+		templateCache:  templateCache,
+		trashRetention: *trashRetention,
+		unlockLimiter:  newRateLimiter(unlockAttempts, unlockWindow),
+		users:          &mysql.UserModel{DB: db},
+		views:          newViewCounter(),
+	}
+
+	// One-shot mode: delete expired snippets and exit
+	if *reapOnce {
+		n, err := app.deleteExpired(context.Background())
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		infoLog.Printf("Reaped %d expired snippets", n)
+		fmt.Printf("Reaped %d expired snippets\n", n)
+		return
 	}
 
+	// Background tasks run until the server shuts down
+	ctx, cancel := context.WithCancel(context.Background())
+	var tasks sync.WaitGroup
+
+	// Permanently remove snippets which stayed in the trash too long
+	runEvery(ctx, &tasks, purgeInterval, app.purgeTrash)
+	// Permanently remove expired snippets
+	runEvery(ctx, &tasks, *reapInterval, app.reapExpired)
+	// Write the counted snippet views to the database
+	runEvery(ctx, &tasks, *viewsFlush, app.flushViews)
+
 	// Initialize a tls.Config struct to hold the non-default TLS settings the server to use
 	tlsConfig := &tls.Config{
 		PreferServerCipherSuites: true,
@@ -117,12 +226,38 @@ func main() {
 	infoLog.Printf("Server started on http://127.0.0.1%s", *addr)
 	fmt.Printf("Server started on http://127.0.0.1%s", *addr)
 
+	// Shut the server down gracefully on SIGINT or SIGTERM
+	shutdown := make(chan error, 1)
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		<-quit
+
+		infoLog.Print("Shutting down the server")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		shutdown <- srv.Shutdown(ctx)
+	}()
+
 	// Use the ListenAndServeTLS() method to start the HTTPS server. We
 	// pass in the paths to the TLS certificate and corresponding private key a
 	// the two parameters.
 	err = srv.ListenAndServeTLS(gopath+"/src/github.com/alekslesik/snippetbox.learn/tls/cert.pem", gopath+"/src/github.com/alekslesik/snippetbox.learn/tls/key.pem")
+	if !errors.Is(err, http.ErrServerClosed) {
+		errorLog.Fatal(err)
+	}
+
+	err = <-shutdown
+	if err != nil {
+		errorLog.Print(err)
+	}
 
-	errorLog.Fatal(err)
+	// Let the background tasks finish their current run
+	cancel()
+	tasks.Wait()
+	// Keep the views counted since the last flush
+	app.flushViews(context.Background())
+	infoLog.Print("Server stopped")
 }
 
 func openDB(dsn string) (*sql.DB, error) {
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Markdown converter for snippets. It isn't configured with the unsafe
+// renderer option, so raw HTML like <script> tags and elements with event
+// handlers is dropped from the output and links with dangerous schemes such
+// as javascript: get an empty href. Attribute syntax is disabled as well, so
+// the only attributes rendered are the ones added by nofollowLinks.
+var md = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(
+		parser.WithASTTransformers(util.Prioritized(nofollowLinks{}, 100)),
+	),
+)
+
+// AST transformer which adds rel="nofollow" to every link
+type nofollowLinks struct{}
+
+func (nofollowLinks) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch n.Kind() {
+		case ast.KindLink, ast.KindAutoLink:
+			n.SetAttributeString("rel", []byte("nofollow"))
+		}
+
+		return ast.WalkContinue, nil
+	})
+}
+
+// Return the markdown text rendered to sanitized HTML
+func markdown(text string) template.HTML {
+	buf := new(bytes.Buffer)
+
+	err := md.Convert([]byte(text), buf)
+	if err != nil {
+		// Fall back to the escaped source text
+		return template.HTML("<pre>" + template.HTMLEscapeString(text) + "</pre>")
+	}
+
+	return template.HTML(buf.String())
+}
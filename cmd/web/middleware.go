@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/alekslesik/snippetbox.learn/pkg/models"
 	"github.com/justinas/nosurf"
@@ -18,6 +20,33 @@ func secureHeaders(next http.Handler) http.Handler {
 	})
 }
 
+// Let the pages of the route be framed by the application itself and the
+// configured embed origins. This replaces the X-Frame-Options header set by
+// secureHeaders with the CSP frame-ancestors directive, which unlike
+// X-Frame-Options takes a list of origins.
+func (app *application) allowFraming(next http.Handler) http.Handler {
+	ancestors := strings.Join(append([]string{"'self'"}, app.embedOrigins...), " ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Del("X-Frame-Options")
+		w.Header().Set("Content-Security-Policy", "frame-ancestors "+ancestors)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Return true if s is a bare origin like https://wiki.example.com, which can
+// go into the frame-ancestors source list without changing the policy
+func validOrigin(s string) bool {
+	if strings.ContainsAny(s, ";,'\"") {
+		return false
+	}
+
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != "" && u.User == nil &&
+		u.Path == "" && u.RawQuery == "" && u.Fragment == ""
+}
+
 // Create a NoSurf middleware function which uses a customized CSRF cookie with
 // the Secure, Path and HttpOnly flags set.
 func noSurf(next http.Handler) http.Handler {
@@ -59,3 +59,27 @@ func TestSecureHeaders(t *testing.T) {
 		t.Errorf("want body to equal %q", "OK")
 	}
 }
+
+func TestValidOrigin(t *testing.T) {
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://wiki.example.com", true},
+		{"http://localhost:8080", true},
+		{"wiki.example.com", false},
+		{"https://wiki.example.com/page", false},
+		{"https://wiki.example.com;script-src", false},
+		{"https://wiki.example.com,https://other.com", false},
+		{"'none'", false},
+		{"https://", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			if got := validOrigin(tt.origin); got != tt.want {
+				t.Errorf("want %v; got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+// Size of the embed frame unless the consumer asks for a smaller one
+const (
+	oembedWidth  = 800
+	oembedHeight = 400
+)
+
+// Path of the snippet pages which can be embedded through oEmbed
+var oembedPathRX = regexp.MustCompile(`^/snippet/([1-9][0-9]*)$`)
+
+// Response of the oEmbed provider, see https://oembed.com
+type oembed struct {
+	XMLName      xml.Name `json:"-" xml:"oembed"`
+	Type         string   `json:"type" xml:"type"`
+	Version      string   `json:"version" xml:"version"`
+	Title        string   `json:"title" xml:"title"`
+	AuthorName   string   `json:"author_name" xml:"author_name"`
+	ProviderName string   `json:"provider_name" xml:"provider_name"`
+	ProviderURL  string   `json:"provider_url" xml:"provider_url"`
+	HTML         string   `json:"html" xml:"html"`
+	Width        int      `json:"width" xml:"width"`
+	Height       int      `json:"height" xml:"height"`
+}
+
+// Return the size limit from the query parameter, the default if it's not
+// set or larger. The second value is false if the parameter is invalid.
+func oembedSize(value string, def int) (int, bool) {
+	if value == "" {
+		return def, true
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	if n > def {
+		return def, true
+	}
+
+	return n, true
+}
+
+// oEmbed provider GET /oembed?url=...&format=json|xml with the optional
+// maxwidth and maxheight parameters. The response embeds the frameable
+// embed page of the snippet.
+func (app *application) oembed(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	format := q.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "xml" {
+		app.clientError(w, http.StatusNotImplemented)
+		return
+	}
+
+	width, ok := oembedSize(q.Get("maxwidth"), oembedWidth)
+	if !ok {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	height, ok := oembedSize(q.Get("maxheight"), oembedHeight)
+	if !ok {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// Only snippet pages of this site can be embedded
+	u, err := url.Parse(q.Get("url"))
+	if err != nil || (u.Host != "" && u.Host != r.Host) {
+		app.notFound(w)
+		return
+	}
+	m := oembedPathRX.FindStringSubmatch(u.Path)
+	if m == nil {
+		app.notFound(w)
+		return
+	}
+	id, _ := strconv.Atoi(m[1])
+
+	// Unfurling isn't a view, it mustn't use up views of the snippet
+	s, err := app.snippets.Peek(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	// Unlisted snippets aren't found by ID and protected ones can't be shown
+	// without the passphrase
+	if s.Unlisted() {
+		app.notFound(w)
+		return
+	}
+	if s.Protected {
+		app.clientError(w, http.StatusUnauthorized)
+		return
+	}
+
+	base := baseURL(r)
+	resp := &oembed{
+		Type:         "rich",
+		Version:      "1.0",
+		Title:        s.Title,
+		AuthorName:   s.UserName,
+		ProviderName: "Snippetbox",
+		ProviderURL:  base + "/",
+		HTML: fmt.Sprintf(`<iframe src="%s" title="%s" width="%d" height="%d" style="border: none"></iframe>`,
+			html.EscapeString(base+snippetURL(s)+"/embed"), html.EscapeString(s.Title), width, height),
+		Width:  width,
+		Height: height,
+	}
+
+	var body []byte
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		body, err = json.Marshal(resp)
+	} else {
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		body, err = xml.Marshal(resp)
+		body = append([]byte(xml.Header), body...)
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	w.Write(body)
+}
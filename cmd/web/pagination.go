@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Number of snippets shown on one page of a listing
+const snippetsPerPage = 10
+
+// Position of the current page in a paginated listing
+type pagination struct {
+	Current int
+	Last    int
+	url     url.URL
+}
+
+// Initialize a pagination for the page of a listing with total items. Links
+// to other pages keep the path and query string of the current URL.
+func newPagination(u *url.URL, page, total int) *pagination {
+	last := (total + snippetsPerPage - 1) / snippetsPerPage
+	if last < 1 {
+		last = 1
+	}
+
+	return &pagination{Current: page, Last: last, url: *u}
+}
+
+func (p *pagination) HasPrev() bool {
+	return p.Current > 1
+}
+
+func (p *pagination) HasNext() bool {
+	return p.Current < p.Last
+}
+
+// Return URL of the previous page
+func (p *pagination) PrevURL() string {
+	return p.pageURL(p.Current - 1)
+}
+
+// Return URL of the next page
+func (p *pagination) NextURL() string {
+	return p.pageURL(p.Current + 1)
+}
+
+func (p *pagination) pageURL(page int) string {
+	q := p.url.Query()
+	q.Set("page", strconv.Itoa(page))
+
+	u := url.URL{Path: p.url.Path, RawQuery: q.Encode()}
+	return u.String()
+}
+
+// Return the page number from the "page" query string parameter. The first
+// page is returned if the parameter is missing, false if it is malformed.
+func pageParam(r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("page")
+	if value == "" {
+		return 1, true
+	}
+
+	page, err := strconv.Atoi(value)
+	if err != nil || page < 1 {
+		return 0, false
+	}
+
+	return page, true
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limits of wrong passphrases for one snippet from one client address
+const (
+	unlockAttempts = 5
+	unlockWindow   = 15 * time.Minute
+)
+
+type attempts struct {
+	count int
+	reset time.Time
+}
+
+// Count failed attempts by key and block the key once it made max failures
+// within the window. Safe for concurrent use.
+type rateLimiter struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	failures map[string]*attempts
+}
+
+func newRateLimiter(max int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		max:      max,
+		window:   window,
+		failures: map[string]*attempts{},
+	}
+}
+
+// Return true if the key hasn't used up its failed attempts yet
+func (l *rateLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	a, ok := l.failures[key]
+	if !ok {
+		return true
+	}
+	if time.Now().After(a.reset) {
+		delete(l.failures, key)
+		return true
+	}
+
+	return a.count < l.max
+}
+
+// Record a failed attempt of the key
+func (l *rateLimiter) Fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	// Forget the keys whose window is over so the map doesn't grow forever
+	for k, a := range l.failures {
+		if now.After(a.reset) {
+			delete(l.failures, k)
+		}
+	}
+
+	a, ok := l.failures[key]
+	if !ok {
+		a = &attempts{reset: now.Add(l.window)}
+		l.failures[key] = a
+	}
+	a.count++
+}
+
+// Return the IP address of the client without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -16,23 +16,75 @@ func (app *application) routes() http.Handler {
 	// our dynamic application routes.
 	dynamicMiddleware := alice.New(app.session.Enable, noSurf, app.authenticate)
 
+	// Embedded snippets are the only pages other sites may frame
+	embedMiddleware := dynamicMiddleware.Append(app.allowFraming)
+
 	// New pat router with REST
 	mux := pat.New()
 	// Use the new dynamic middleware chain followed by the appropriate handler function.
 	mux.Get("/", dynamicMiddleware.ThenFunc(app.home))
+	mux.Get("/snippets", dynamicMiddleware.ThenFunc(app.archive))
+	mux.Get("/search", dynamicMiddleware.ThenFunc(app.search))
+	mux.Get("/compare", dynamicMiddleware.ThenFunc(app.compareSnippets))
+	mux.Get("/tag/:name", dynamicMiddleware.ThenFunc(app.showTag))
 	mux.Get("/snippet/create", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.createSnippetForm))
 	mux.Post("/snippet/create", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.createSnippet))
 	mux.Get("/snippet/:id", dynamicMiddleware.ThenFunc(app.showSnippet))
+	mux.Get("/snippet/:id/raw", dynamicMiddleware.ThenFunc(app.rawSnippet))
+	mux.Get("/snippet/:id/download", dynamicMiddleware.ThenFunc(app.downloadSnippet))
+	mux.Get("/snippet/:id/zip", dynamicMiddleware.ThenFunc(app.zipSnippet))
+	mux.Get("/snippet/:id/embed", embedMiddleware.ThenFunc(app.embedSnippet))
+	mux.Post("/snippet/:id/unlock", dynamicMiddleware.ThenFunc(app.unlockSnippet))
+	mux.Post("/snippet/:id/fork", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.forkSnippet))
+	mux.Post("/snippet/:id/star", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.starSnippet))
+	mux.Post("/snippet/:id/comment", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.commentSnippet))
+	mux.Get("/s/:slug", dynamicMiddleware.ThenFunc(app.showSharedSnippet))
+	mux.Get("/s/:slug/raw", dynamicMiddleware.ThenFunc(app.rawSnippet))
+	mux.Get("/s/:slug/download", dynamicMiddleware.ThenFunc(app.downloadSnippet))
+	mux.Get("/s/:slug/zip", dynamicMiddleware.ThenFunc(app.zipSnippet))
+	mux.Get("/s/:slug/embed", embedMiddleware.ThenFunc(app.embedSnippet))
+	mux.Post("/s/:slug/unlock", dynamicMiddleware.ThenFunc(app.unlockSharedSnippet))
+	mux.Post("/s/:slug/fork", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.forkSnippet))
+	mux.Post("/s/:slug/star", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.starSnippet))
+	mux.Post("/s/:slug/comment", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.commentSnippet))
+	mux.Get("/snippet/:id/history", dynamicMiddleware.ThenFunc(app.snippetHistory))
+	mux.Get("/snippet/:id/diff", dynamicMiddleware.ThenFunc(app.snippetDiff))
+	mux.Get("/snippet/:id/stats", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.snippetStats))
+	mux.Get("/snippet/:id/edit", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.editSnippetForm))
+	mux.Post("/snippet/:id/edit", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.editSnippet))
+	mux.Post("/snippet/:id/extend", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.extendSnippet))
+	mux.Post("/snippet/:id/delete", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.deleteSnippet))
+	mux.Post("/snippet/:id/restore", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.restoreSnippet))
+	mux.Post("/snippet/:id/collect", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.collectSnippet))
+	mux.Post("/s/:slug/collect", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.collectSnippet))
+	mux.Get("/collection/create", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.createCollectionForm))
+	mux.Post("/collection/create", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.createCollection))
+	mux.Get("/collection/:id/edit", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.editCollectionForm))
+	mux.Post("/collection/:id/edit", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.editCollection))
+	mux.Post("/collection/:id/delete", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.deleteCollection))
+	mux.Post("/collection/:id/move", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.moveCollectionSnippet))
+	mux.Post("/collection/:id/remove", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.removeCollectionSnippet))
+	mux.Get("/c/:id", dynamicMiddleware.ThenFunc(app.showCollection))
+	mux.Get("/comment/:id/edit", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.editCommentForm))
+	mux.Post("/comment/:id/edit", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.editComment))
+	mux.Post("/comment/:id/delete", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.deleteComment))
 	mux.Get("/user/signup", dynamicMiddleware.ThenFunc(app.signupUserForm))
 	mux.Post("/user/signup", dynamicMiddleware.ThenFunc(app.signupUser))
 	mux.Get("/user/login", dynamicMiddleware.ThenFunc(app.loginUserForm))
 	mux.Post("/user/login", dynamicMiddleware.ThenFunc(app.loginUser))
+	mux.Get("/user/snippets", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.userSnippets))
+	mux.Get("/user/trash", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.userTrash))
+	mux.Get("/user/starred", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.userStarred))
+	mux.Get("/user/collections", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.userCollections))
 	mux.Post("/user/logout", dynamicMiddleware.Append(app.requireAuthenticatedUser).ThenFunc(app.logoutUser))
 	mux.Get("/about", dynamicMiddleware.ThenFunc(app.about))
 
 	fileServer := http.FileServer(http.Dir("./ui/static/"))
 	mux.Get("/static/", http.StripPrefix("/static", fileServer))
 
+	// oEmbed consumers don't have sessions
+	mux.Get("/oembed", http.HandlerFunc(app.oembed))
+
 	// for end-to-end testing
 	mux.Get("/ping", http.HandlerFunc(ping))
 
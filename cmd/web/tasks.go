@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// How often the trash is checked for snippets to be removed permanently
+const purgeInterval = time.Hour
+
+// Run the task at once and then every interval in a separate goroutine until
+// ctx is cancelled. The goroutine is tracked by wg, so the caller can wait
+// for the running task to finish on shutdown.
+func runEvery(ctx context.Context, wg *sync.WaitGroup, interval time.Duration, task func(ctx context.Context)) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			task(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Remove snippets which stayed in the trash longer than the configured
+// retention period
+func (app *application) purgeTrash(ctx context.Context) {
+	n, err := app.snippets.Purge(app.trashRetention)
+	if err != nil {
+		app.errorLog.Print(err)
+	} else if n > 0 {
+		app.infoLog.Printf("Purged %d snippets from the trash", n)
+	}
+}
+
+// Remove expired snippets from the database
+func (app *application) reapExpired(ctx context.Context) {
+	n, err := app.deleteExpired(ctx)
+	if err != nil {
+		app.errorLog.Print(err)
+	}
+	if n > 0 {
+		app.infoLog.Printf("Reaped %d expired snippets", n)
+	}
+}
+
+// Delete expired snippets in batches of app.reapBatch, so the table isn't
+// locked for long, until there are none left or ctx is cancelled. Return the
+// number of deleted snippets.
+func (app *application) deleteExpired(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n, err := app.snippets.DeleteExpired(app.reapBatch)
+		total += n
+		if err != nil || n < app.reapBatch {
+			return total, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return total, nil
+		default:
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models/mock"
+)
+
+// Snippet model with a number of expired snippets left to delete
+type expiredSnippets struct {
+	mock.SnippetModel
+	left    int
+	batches int
+	err     error
+}
+
+func (m *expiredSnippets) DeleteExpired(limit int) (int, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+
+	m.batches++
+	n := limit
+	if m.left < n {
+		n = m.left
+	}
+	m.left -= n
+	return n, nil
+}
+
+func TestDeleteExpired(t *testing.T) {
+	tests := []struct {
+		name        string
+		expired     int
+		err         error
+		wantDeleted int
+		wantBatches int
+		wantErr     bool
+	}{
+		{"Nothing expired", 0, nil, 0, 1, false},
+		{"One batch", 3, nil, 3, 1, false},
+		{"Full batches", 20, nil, 20, 3, false},
+		{"Several batches", 25, nil, 25, 3, false},
+		{"Error", 5, errors.New("test error"), 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snippets := &expiredSnippets{left: tt.expired, err: tt.err}
+			app := &application{reapBatch: 10, snippets: snippets}
+
+			n, err := app.deleteExpired(context.Background())
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("want error %v; got %v", tt.wantErr, err)
+			}
+			if n != tt.wantDeleted {
+				t.Errorf("want %d deleted; got %d", tt.wantDeleted, n)
+			}
+			if snippets.batches != tt.wantBatches {
+				t.Errorf("want %d batches; got %d", tt.wantBatches, snippets.batches)
+			}
+		})
+	}
+}
+
+func TestRunEvery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	runs := make(chan struct{}, 1)
+	runEvery(ctx, &wg, time.Millisecond, func(ctx context.Context) {
+		select {
+		case runs <- struct{}{}:
+		default:
+		}
+	})
+
+	// The task runs at once and then again on the next tick
+	for i := 0; i < 2; i++ {
+		select {
+		case <-runs:
+		case <-time.After(time.Second):
+			t.Fatal("task didn't run")
+		}
+	}
+
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("task didn't stop after cancel")
+	}
+}
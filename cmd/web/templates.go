@@ -3,21 +3,42 @@ package main
 import (
 	"html/template"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/alekslesik/snippetbox.learn/pkg/diff"
 	"github.com/alekslesik/snippetbox.learn/pkg/forms"
 	"github.com/alekslesik/snippetbox.learn/pkg/models"
 )
 
 type templateData struct {
 	AuthenticatedUser *models.User
+	BaseURL           string
+	Chart             []*chartBar
+	Collection        *models.Collection
+	Collections       []*models.Collection
 	
 	Flash             string
 	CurrentYear       int
 	CSRFToken         string
+	Comment           *models.Comment
+	CommentForm       *forms.Form
+	Comments          []*models.Comment
 	Form              *forms.Form
+	FromRevision      *models.Revision
+	Hunks             []diff.Hunk
+	Languages         []language
+	Layout            string
+	Pagination        *pagination
+	Query             string
+	Revisions         []*models.Revision
 	Snippet           *models.Snippet
 	Snippets          []*models.Snippet
+	Starred           bool
+	Tag               string
+	Tags              []*models.Tag
+	ToRevision        *models.Revision
 }
 
 // Return nicely formatted string of time.Time object
@@ -30,11 +51,79 @@ func humanDate(t time.Time) string {
 	return t.UTC().Format("02 Jan 2006 at 15:04")
 }
 
+// Return HTML escaped text with every word of the search query wrapped
+// in a <mark> element
+func highlight(text, query string) template.HTML {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	rx := regexp.MustCompile("(?i)" + strings.Join(words, "|"))
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range rx.FindAllStringIndex(text, -1) {
+		b.WriteString(template.HTMLEscapeString(text[last:loc[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(template.HTMLEscapeString(text[loc[0]:loc[1]]))
+		b.WriteString("</mark>")
+		last = loc[1]
+	}
+	b.WriteString(template.HTMLEscapeString(text[last:]))
+
+	return template.HTML(b.String())
+}
+
+// Return weight of the tag in the tag cloud from 1 to 5 relative to the most
+// used tag of the cloud
+func tagWeight(tag *models.Tag, cloud []*models.Tag) int {
+	max := 0
+	for _, t := range cloud {
+		if t.Count > max {
+			max = t.Count
+		}
+	}
+	if max == 0 {
+		return 1
+	}
+
+	return 1 + 4*tag.Count/max
+}
+
+// Return indexes of the file entries of the snippet form, one more than it
+// has, so there is always an empty entry to add a file
+func fileEntries(form *forms.Form) []int {
+	n := form.Count(fileFields...) + 1
+	entries := make([]int, n)
+	for i := range entries {
+		entries[i] = i
+	}
+	return entries
+}
+
+// Return the comment followed by its replies
+func thread(c *models.Comment) []*models.Comment {
+	return append([]*models.Comment{c}, c.Replies...)
+}
+
 // Initialize a template.FuncMap object and store it in a global variable. This
 // essentially a string-keyed map which acts as a lookup between the names of o
 // custom template functions and the functions themselves.
 var functions = template.FuncMap {
-	"humanDate": humanDate,
+	"fileEntries":   fileEntries,
+	"thread":        thread,
+	"humanDate":     humanDate,
+	"highlight":     highlight,
+	"highlightCode": highlightCode,
+	"markdown":      markdown,
+	"numberedCode":  numberedCode,
+	"snippetURL":    snippetURL,
+	"collectionURL": collectionURL,
+	"tagWeight":     tagWeight,
 }
 
 func newTemplateCache(dir string) (map[string]*template.Template, error) {
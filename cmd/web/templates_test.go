@@ -1,8 +1,12 @@
 package main
 
 import (
+	"html/template"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
 )
 
 // Simple test pattern
@@ -63,3 +67,181 @@ func TestHumanDate(t *testing.T) {
 		})
 	}
 }
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		want  template.HTML
+	}{
+		{
+			name:  "Single word",
+			text:  "An old silent pond",
+			query: "pond",
+			want:  "An old silent <mark>pond</mark>",
+		},
+		{
+			name:  "Case insensitive",
+			text:  "An old silent pond",
+			query: "OLD Pond",
+			want:  "An <mark>old</mark> silent <mark>pond</mark>",
+		},
+		{
+			name:  "Escaped text",
+			text:  "<script>alert(1)</script>",
+			query: "alert",
+			want:  "&lt;script&gt;<mark>alert</mark>(1)&lt;/script&gt;",
+		},
+		{
+			name:  "Regexp characters",
+			text:  "a.b(c)",
+			query: "(c)",
+			want:  "a.b<mark>(c)</mark>",
+		},
+		{
+			name:  "Empty query",
+			text:  "a < b",
+			query: " ",
+			want:  "a &lt; b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := highlight(tt.text, tt.query)
+
+			if got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTagWeight(t *testing.T) {
+	cloud := []*models.Tag{
+		{Name: "go", Count: 1},
+		{Name: "sql", Count: 5},
+		{Name: "css", Count: 10},
+	}
+
+	tests := []struct {
+		name string
+		tag  *models.Tag
+		want int
+	}{
+		{"Least used", cloud[0], 1},
+		{"Middle", cloud[1], 3},
+		{"Most used", cloud[2], 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tagWeight(tt.tag, cloud)
+
+			if got != tt.want {
+				t.Errorf("want %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHighlightCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		lang     string
+		want     string
+		dontWant string
+	}{
+		{
+			name: "Go keyword",
+			code: "package main",
+			lang: "go",
+			want: ">package</span>",
+		},
+		{
+			name:     "Escaped markup",
+			code:     "<script>alert(1)</script>",
+			lang:     "html",
+			want:     "&lt;",
+			dontWant: "<script>",
+		},
+		{
+			name:     "Unknown language",
+			code:     "<b>bold</b>",
+			lang:     "brainfuck++",
+			want:     "&lt;b&gt;bold&lt;/b&gt;",
+			dontWant: "<b>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(highlightCode(tt.code, tt.lang))
+
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("want %q to contain %q", got, tt.want)
+			}
+
+			if tt.dontWant != "" && strings.Contains(got, tt.dontWant) {
+				t.Errorf("want %q not to contain %q", got, tt.dontWant)
+			}
+		})
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		want     string
+		dontWant string
+	}{
+		{
+			name: "Emphasis",
+			text: "*An old* silent **pond**",
+			want: "<p><em>An old</em> silent <strong>pond</strong></p>",
+		},
+		{
+			name: "Nofollow link",
+			text: "[pond](https://example.com)",
+			want: `<a href="https://example.com" rel="nofollow">pond</a>`,
+		},
+		{
+			name: "Nofollow autolink",
+			text: "https://example.com",
+			want: `rel="nofollow"`,
+		},
+		{
+			name:     "Script",
+			text:     "<script>alert(1)</script>",
+			want:     "raw HTML omitted",
+			dontWant: "<script>",
+		},
+		{
+			name:     "Event handler",
+			text:     `<img src="x" onerror="alert(1)">`,
+			dontWant: "onerror",
+		},
+		{
+			name:     "Javascript link",
+			text:     "[pond](javascript:alert(1))",
+			dontWant: "javascript:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(markdown(tt.text))
+
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("want %q to contain %q", got, tt.want)
+			}
+
+			if tt.dontWant != "" && strings.Contains(got, tt.dontWant) {
+				t.Errorf("want %q not to contain %q", got, tt.dontWant)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"regexp"
 	"testing"
@@ -18,7 +19,7 @@ import (
 
 // Define a regular expression which captures the CSRF token value from the
 // HTML for our user signup page.
-var csrfTokenRX = regexp.MustCompile(`<input type="hidden" name="csrf_token" value='(.?)`)
+var csrfTokenRX = regexp.MustCompile(`<input type="hidden" name="csrf_token" value='(.+)'>`)
 
 func extractCSRFToken(t *testing.T, body []byte) string {
 	// Use the FindSubmatch method to extract the token from the HTML body.
@@ -61,12 +62,18 @@ func newTestApplication(t *testing.T, auth bool) *application {
 	// database models.
 	return &application{
 		gopath:        gopath,
+		collections:   &mock.CollectionModel{},
+		comments:      &mock.CommentModel{},
 		errorLog:      log.New(ioutil.Discard, "", 0),
 		infoLog:       log.New(ioutil.Discard, "", 0),
+		maxExpiry:     365 * 24 * time.Hour,
+		minExpiry:     time.Minute,
 		session:       session,
 		snippets:      &mock.SnippetModel{},
 		templateCache: templateCache,
+		unlockLimiter: newRateLimiter(unlockAttempts, unlockWindow),
 		users:         &mock.UserModel{},
+		views:         newViewCounter(),
 	}
 }
 
@@ -91,12 +98,18 @@ func newTestApplicationERR(t *testing.T) *application {
 	// database models.
 	return &application{
 		gopath:        gopath,
+		collections:   &mock.CollectionModel{},
+		comments:      &mock.CommentModel{},
 		errorLog:      log.New(ioutil.Discard, "", 0),
 		infoLog:       log.New(ioutil.Discard, "", 0),
+		maxExpiry:     365 * 24 * time.Hour,
+		minExpiry:     time.Minute,
 		session:       session,
 		snippets:      &mock.SnippetModelERR{},
 		templateCache: templateCache,
+		unlockLimiter: newRateLimiter(unlockAttempts, unlockWindow),
 		users:         &mock.UserModel{},
+		views:         newViewCounter(),
 	}
 }
 
@@ -165,3 +178,37 @@ func (ts *testServer) getERR(t *testing.T, urlPath string) int {
 
 	return rs.StatusCode
 }
+
+// Implement a postForm method on our custom testServer type. This makes a POST
+// request with the given form data to a given url path on the test server,
+// and returns the response status code, headers and body.
+func (ts *testServer) postForm(t *testing.T, urlPath string, form url.Values) (int, http.Header, []byte) {
+	rs, err := ts.Client().PostForm(ts.URL+urlPath, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rs.Body.Close()
+	body, err := ioutil.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, rs.Header, body
+}
+
+// Log in the mock user, so that subsequent requests made by the test server
+// client carry an authenticated session cookie.
+func (ts *testServer) login(t *testing.T) {
+	_, _, body := ts.get(t, "/user/login")
+
+	form := url.Values{}
+	form.Add("email", "alekslesik@gmail.com")
+	form.Add("password", "password")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	code, _, _ := ts.postForm(t, "/user/login", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("login: want %d, got %d", http.StatusSeeOther, code)
+	}
+}
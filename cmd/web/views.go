@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+// Number of days shown on the stats page
+const statsDays = 30
+
+type viewKey struct {
+	id  int
+	day time.Time
+}
+
+// Count snippet views per day in memory, so requests don't wait for the
+// database. The counts are written by flushViews. Safe for concurrent use.
+type viewCounter struct {
+	mu     sync.Mutex
+	counts map[viewKey]int
+}
+
+func newViewCounter() *viewCounter {
+	return &viewCounter{counts: map[viewKey]int{}}
+}
+
+// Return the UTC day t belongs to
+func day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// Count a view of the snippet today
+func (c *viewCounter) Add(id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts[viewKey{id, day(time.Now())}]++
+}
+
+// Return the counted views and start counting from zero
+func (c *viewCounter) Take() []*models.ViewCount {
+	c.mu.Lock()
+	counts := c.counts
+	c.counts = map[viewKey]int{}
+	c.mu.Unlock()
+
+	views := make([]*models.ViewCount, 0, len(counts))
+	for k, n := range counts {
+		views = append(views, &models.ViewCount{SnippetID: k.id, Day: k.day, Views: n})
+	}
+
+	return views
+}
+
+// Count the views again, e.g. if they couldn't be stored
+func (c *viewCounter) Restore(views []*models.ViewCount) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, v := range views {
+		c.counts[viewKey{v.SnippetID, v.Day}] += v.Views
+	}
+}
+
+// Write the views counted since the last flush to the database. On failure
+// they are kept for the next flush.
+func (app *application) flushViews(ctx context.Context) {
+	views := app.views.Take()
+	if len(views) == 0 {
+		return
+	}
+
+	err := app.snippets.RecordViews(views)
+	if err != nil {
+		app.errorLog.Print(err)
+		app.views.Restore(views)
+	}
+}
+
+// Views of one day of the stats chart. Percent is relative to the day with
+// the most views.
+type chartBar struct {
+	Day     time.Time
+	Views   int
+	Percent int
+}
+
+// Return the bars of the last days up to the day of end, days without views
+// included
+func viewsChart(views []*models.ViewCount, end time.Time, days int) []*chartBar {
+	byDay := map[time.Time]int{}
+	max := 0
+	for _, v := range views {
+		byDay[day(v.Day)] += v.Views
+		if byDay[day(v.Day)] > max {
+			max = byDay[day(v.Day)]
+		}
+	}
+
+	bars := make([]*chartBar, days)
+	first := day(end).AddDate(0, 0, 1-days)
+	for i := range bars {
+		d := first.AddDate(0, 0, i)
+		b := &chartBar{Day: d, Views: byDay[d]}
+		if max > 0 {
+			b.Percent = 100 * b.Views / max
+		}
+		bars[i] = b
+	}
+
+	return bars
+}
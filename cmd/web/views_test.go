@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+	"github.com/alekslesik/snippetbox.learn/pkg/models/mock"
+)
+
+// Snippet model which stores the recorded views or fails with err
+type viewsSnippets struct {
+	mock.SnippetModel
+	recorded []*models.ViewCount
+	err      error
+}
+
+func (m *viewsSnippets) RecordViews(views []*models.ViewCount) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.recorded = append(m.recorded, views...)
+	return nil
+}
+
+// Sum the views of the snippet
+func sumViews(views []*models.ViewCount, id int) int {
+	n := 0
+	for _, v := range views {
+		if v.SnippetID == id {
+			n += v.Views
+		}
+	}
+	return n
+}
+
+func TestFlushViews(t *testing.T) {
+	snippets := &viewsSnippets{err: errors.New("test error")}
+	app := &application{
+		errorLog: log.New(ioutil.Discard, "", 0),
+		snippets: snippets,
+		views:    newViewCounter(),
+	}
+
+	app.views.Add(1)
+	app.views.Add(1)
+	app.views.Add(3)
+
+	// Failed views are kept for the next flush
+	app.flushViews(context.Background())
+	if len(snippets.recorded) != 0 {
+		t.Errorf("want no recorded views; got %d", len(snippets.recorded))
+	}
+
+	snippets.err = nil
+	app.views.Add(3)
+	app.flushViews(context.Background())
+
+	if n := sumViews(snippets.recorded, 1); n != 2 {
+		t.Errorf("want 2 views of snippet 1; got %d", n)
+	}
+	if n := sumViews(snippets.recorded, 3); n != 2 {
+		t.Errorf("want 2 views of snippet 3; got %d", n)
+	}
+	if len(snippets.recorded) != 2 {
+		t.Errorf("want one count per snippet and day; got %d", len(snippets.recorded))
+	}
+
+	// Nothing is left after a flush
+	if views := app.views.Take(); len(views) != 0 {
+		t.Errorf("want no views left; got %d", len(views))
+	}
+}
+
+func TestViewsChart(t *testing.T) {
+	end := time.Date(2022, 6, 10, 15, 4, 0, 0, time.UTC)
+	views := []*models.ViewCount{
+		{SnippetID: 1, Day: time.Date(2022, 6, 8, 0, 0, 0, 0, time.UTC), Views: 2},
+		{SnippetID: 1, Day: time.Date(2022, 6, 10, 0, 0, 0, 0, time.UTC), Views: 4},
+	}
+
+	bars := viewsChart(views, end, 3)
+
+	want := []chartBar{
+		{Day: time.Date(2022, 6, 8, 0, 0, 0, 0, time.UTC), Views: 2, Percent: 50},
+		{Day: time.Date(2022, 6, 9, 0, 0, 0, 0, time.UTC), Views: 0, Percent: 0},
+		{Day: time.Date(2022, 6, 10, 0, 0, 0, 0, time.UTC), Views: 4, Percent: 100},
+	}
+	if len(bars) != len(want) {
+		t.Fatalf("want %d bars; got %d", len(want), len(bars))
+	}
+	for i, b := range bars {
+		if !b.Day.Equal(want[i].Day) || b.Views != want[i].Views || b.Percent != want[i].Percent {
+			t.Errorf("bar %d: want %+v; got %+v", i, want[i], *b)
+		}
+	}
+}
+
+// Views of other users are counted, views of the author are not
+func TestCountViews(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.get(t, "/snippet/1")
+	ts.get(t, "/snippet/1/raw")
+	ts.login(t)
+	ts.get(t, "/snippet/1")
+
+	if n := sumViews(app.views.Take(), 1); n != 2 {
+		t.Errorf("want 2 views; got %d", n)
+	}
+}
+
+// snippetStats() GET /snippet/:id/stats
+func TestSnippetStats(t *testing.T) {
+	app := newTestApplication(t, true)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	ts.login(t)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody []byte
+	}{
+		{"Own snippet", "/snippet/1/stats", http.StatusOK, []byte("Total views: 3")},
+		{"Chart", "/snippet/1/stats", http.StatusOK, []byte("height: 100%")},
+		{"Foreign snippet", "/snippet/3/stats", http.StatusForbidden, nil},
+		{"Non-existent ID", "/snippet/2/stats", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
@@ -3,10 +3,12 @@ module github.com/alekslesik/snippetbox.learn
 go 1.15
 
 require (
+	github.com/alecthomas/chroma v0.10.0
 	github.com/bmizerany/pat v0.0.0-20210406213842-e4b6760bdd6f
 	github.com/go-sql-driver/mysql v1.5.0
 	github.com/golangcollege/sessions v1.2.0
 	github.com/justinas/alice v1.2.0
 	github.com/justinas/nosurf v1.1.1
+	github.com/yuin/goldmark v1.4.1
 	golang.org/x/crypto v0.0.0-20200317142112-1b76d66859c6
 )
@@ -0,0 +1,329 @@
+// Package diff computes line-based differences between two texts.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kind of change of a single line
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// Return name of the operation, "equal", "insert" or "delete"
+func (o Op) String() string {
+	switch o {
+	case Insert:
+		return "insert"
+	case Delete:
+		return "delete"
+	default:
+		return "equal"
+	}
+}
+
+// Line of the difference. OldNumber and NewNumber are 1-based line numbers in
+// the old and new text, zero if the line is missing from that text.
+type Line struct {
+	Op        Op
+	Text      string
+	OldNumber int
+	NewNumber int
+}
+
+// Consecutive group of changed lines surrounded by unchanged context lines
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []Line
+}
+
+// Return the unified diff header of the hunk like "@@ -1,3 +1,4 @@"
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+}
+
+// Limit of edits searched for before two texts are considered completely
+// different. It bounds the memory used by the diff of two large texts.
+const maxEdits = 1000
+
+// Compare the old and new texts line by line and return all lines of both
+// texts in order, each one marked as equal, inserted or deleted.
+func Lines(old, new string) []Line {
+	a, b := split(old), split(new)
+
+	// Skip the common prefix and suffix, only the middle part needs a search
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []Op
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, Equal)
+	}
+	ops = append(ops, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, Equal)
+	}
+
+	// Attach text and line numbers to the operations
+	lines := make([]Line, len(ops))
+	x, y := 0, 0
+	for i, op := range ops {
+		switch op {
+		case Equal:
+			lines[i] = Line{Op: Equal, Text: a[x], OldNumber: x + 1, NewNumber: y + 1}
+			x++
+			y++
+		case Delete:
+			lines[i] = Line{Op: Delete, Text: a[x], OldNumber: x + 1}
+			x++
+		case Insert:
+			lines[i] = Line{Op: Insert, Text: b[y], NewNumber: y + 1}
+			y++
+		}
+	}
+
+	return lines
+}
+
+// Group the changed lines into hunks with up to context unchanged lines
+// around every change. No hunks are returned for equal texts.
+func Hunks(lines []Line, context int) []Hunk {
+	var hunks []Hunk
+
+	// Number of old and new lines before the position pos
+	oldBefore, newBefore, pos := 0, 0, 0
+
+	for i := 0; i < len(lines); {
+		if lines[i].Op == Equal {
+			i++
+			continue
+		}
+
+		// Start the hunk context lines before the first change
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		// Extend the hunk while the next change is close enough to share context
+		end := i
+		for end < len(lines) {
+			if lines[end].Op != Equal {
+				end++
+				continue
+			}
+			next := end
+			for next < len(lines) && lines[next].Op == Equal {
+				next++
+			}
+			if next == len(lines) || next-end > 2*context {
+				end += min(context, next-end)
+				break
+			}
+			end = next
+		}
+
+		for ; pos < start; pos++ {
+			if lines[pos].Op != Insert {
+				oldBefore++
+			}
+			if lines[pos].Op != Delete {
+				newBefore++
+			}
+		}
+
+		h := Hunk{Lines: lines[start:end]}
+		for _, l := range h.Lines {
+			if l.Op != Insert {
+				h.OldLines++
+			}
+			if l.Op != Delete {
+				h.NewLines++
+			}
+		}
+
+		// An empty range starts at the line preceding it, like in GNU diff
+		h.OldStart, h.NewStart = oldBefore, newBefore
+		if h.OldLines > 0 {
+			h.OldStart++
+		}
+		if h.NewLines > 0 {
+			h.NewStart++
+		}
+
+		hunks = append(hunks, h)
+		i = end
+	}
+
+	return hunks
+}
+
+// Lines shown next to each other in a side-by-side diff. Old is nil if the
+// new line has no counterpart in the old text and vice versa. Both are the
+// same line if it's unchanged.
+type Pair struct {
+	Old *Line
+	New *Line
+}
+
+// Return the lines of the hunk paired up for a side-by-side diff. Deleted
+// lines are paired with the lines inserted in their place.
+func (h Hunk) Split() []Pair {
+	var pairs []Pair
+	var deleted, inserted []*Line
+
+	flush := func() {
+		for i := 0; i < len(deleted) || i < len(inserted); i++ {
+			var p Pair
+			if i < len(deleted) {
+				p.Old = deleted[i]
+			}
+			if i < len(inserted) {
+				p.New = inserted[i]
+			}
+			pairs = append(pairs, p)
+		}
+		deleted, inserted = nil, nil
+	}
+
+	for i := range h.Lines {
+		l := &h.Lines[i]
+		switch l.Op {
+		case Delete:
+			// A deletion after insertions starts a new change
+			if len(inserted) > 0 {
+				flush()
+			}
+			deleted = append(deleted, l)
+		case Insert:
+			inserted = append(inserted, l)
+		default:
+			flush()
+			pairs = append(pairs, Pair{Old: l, New: l})
+		}
+	}
+	flush()
+
+	return pairs
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Split the text to lines. Windows line endings are normalized and a final
+// line break doesn't produce an extra empty line.
+func split(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Find the shortest edit script transforming a into b with the Myers
+// algorithm and return it as a list of operations.
+func myers(a, b []string) []Op {
+	n, m := len(a), len(b)
+	max := n + m
+	if max > maxEdits {
+		max = maxEdits
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+
+	// trace[d] keeps the furthest reaching x of the diagonals -d-1..d+1
+	// before step d, it is used to backtrack the edit path
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(trace, n, m)
+			}
+		}
+	}
+
+	// The texts are too different, replace one with another completely
+	ops := make([]Op, 0, n+m)
+	for i := 0; i < n; i++ {
+		ops = append(ops, Delete)
+	}
+	for i := 0; i < m; i++ {
+		ops = append(ops, Insert)
+	}
+	return ops
+}
+
+func backtrack(trace [][]int, x, y int) []Op {
+	var ops []Op
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		// Diagonal k is stored at index k+d+1 of the trace step
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[k-1+d+1] < v[k+1+d+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+d+1]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, Equal)
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, Insert)
+				y--
+			} else {
+				ops = append(ops, Delete)
+				x--
+			}
+		}
+	}
+
+	// Reverse the operations collected from the end
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+
+	return ops
+}
@@ -0,0 +1,195 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Render lines in the unified diff notation for easy comparison
+func unified(lines []Line) string {
+	var b strings.Builder
+	for _, l := range lines {
+		switch l.Op {
+		case Equal:
+			b.WriteString(" ")
+		case Insert:
+			b.WriteString("+")
+		case Delete:
+			b.WriteString("-")
+		}
+		b.WriteString(l.Text)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want string
+	}{
+		{
+			name: "Equal",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: " a\n b\n",
+		},
+		{
+			name: "Empty old",
+			old:  "",
+			new:  "a\nb",
+			want: "+a\n+b\n",
+		},
+		{
+			name: "Empty new",
+			old:  "a\nb",
+			new:  "",
+			want: "-a\n-b\n",
+		},
+		{
+			name: "Changed line",
+			old:  "a\nb\nc",
+			new:  "a\nB\nc",
+			want: " a\n-b\n+B\n c\n",
+		},
+		{
+			name: "Inserted and deleted lines",
+			old:  "a\nb\nc\nd\ne",
+			new:  "b\nc\nx\nd\ne\nf",
+			want: "-a\n b\n c\n+x\n d\n e\n+f\n",
+		},
+		{
+			name: "Windows line endings",
+			old:  "a\r\nb\r\n",
+			new:  "a\nb\n",
+			want: " a\n b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unified(Lines(tt.old, tt.new))
+
+			if got != tt.want {
+				t.Errorf("want\n%s\ngot\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLinesNumbers(t *testing.T) {
+	got := Lines("a\nb\nc", "a\nc\nd")
+	want := []Line{
+		{Op: Equal, Text: "a", OldNumber: 1, NewNumber: 1},
+		{Op: Delete, Text: "b", OldNumber: 2},
+		{Op: Equal, Text: "c", OldNumber: 3, NewNumber: 2},
+		{Op: Insert, Text: "d", NewNumber: 3},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+}
+
+func TestLinesTooDifferent(t *testing.T) {
+	var old, new []string
+	for i := 0; i < maxEdits; i++ {
+		old = append(old, "old")
+		new = append(new, "new")
+	}
+
+	lines := Lines(strings.Join(old, "\n"), strings.Join(new, "\n"))
+
+	if len(lines) != 2*maxEdits {
+		t.Fatalf("want %d lines; got %d", 2*maxEdits, len(lines))
+	}
+	if lines[0].Op != Delete || lines[len(lines)-1].Op != Insert {
+		t.Errorf("want all old lines deleted and all new lines inserted")
+	}
+}
+
+func TestHunks(t *testing.T) {
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12"
+	new := "1\n2\n3\nfour\n5\n6\n7\n8\n9\n10\n11\n12\n13"
+
+	hunks := Hunks(Lines(old, new), 2)
+
+	if len(hunks) != 2 {
+		t.Fatalf("want 2 hunks; got %d", len(hunks))
+	}
+
+	tests := []struct {
+		hunk       Hunk
+		wantHeader string
+		wantLines  string
+	}{
+		{hunks[0], "@@ -2,5 +2,5 @@", " 2\n 3\n-4\n+four\n 5\n 6\n"},
+		{hunks[1], "@@ -11,2 +11,3 @@", " 11\n 12\n+13\n"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.hunk.Header(); got != tt.wantHeader {
+			t.Errorf("want header %q; got %q", tt.wantHeader, got)
+		}
+		if got := unified(tt.hunk.Lines); got != tt.wantLines {
+			t.Errorf("want lines\n%s\ngot\n%s", tt.wantLines, got)
+		}
+	}
+}
+
+func TestHunksEmptyRange(t *testing.T) {
+	hunks := Hunks(Lines("", "a\nb"), 3)
+
+	if len(hunks) != 1 {
+		t.Fatalf("want 1 hunk; got %d", len(hunks))
+	}
+	if got := hunks[0].Header(); got != "@@ -0,0 +1,2 @@" {
+		t.Errorf("want header %q; got %q", "@@ -0,0 +1,2 @@", got)
+	}
+}
+
+func TestHunksEqual(t *testing.T) {
+	if hunks := Hunks(Lines("a\nb", "a\nb"), 3); len(hunks) != 0 {
+		t.Errorf("want no hunks; got %d", len(hunks))
+	}
+}
+
+func TestHunkSplit(t *testing.T) {
+	old := "1\n2\n3\n4"
+	new := "1\ntwo\nthree\n3.5\n4\n5"
+
+	hunks := Hunks(Lines(old, new), 1)
+	if len(hunks) != 1 {
+		t.Fatalf("want 1 hunk; got %d", len(hunks))
+	}
+
+	// Text of the old and new side of every pair, "" for a missing side
+	want := [][2]string{
+		{"1", "1"},
+		{"2", "two"},
+		{"3", "three"},
+		{"", "3.5"},
+		{"4", "4"},
+		{"", "5"},
+	}
+
+	pairs := hunks[0].Split()
+	if len(pairs) != len(want) {
+		t.Fatalf("want %d pairs; got %d", len(want), len(pairs))
+	}
+	for i, p := range pairs {
+		var got [2]string
+		if p.Old != nil {
+			got[0] = p.Old.Text
+		}
+		if p.New != nil {
+			got[1] = p.New.Text
+		}
+		if got != want[i] {
+			t.Errorf("pair %d: want %q; got %q", i, want[i], got)
+		}
+	}
+}
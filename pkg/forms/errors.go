@@ -1,5 +1,7 @@
 package forms
 
+import "fmt"
+
 // Type to hold validation error messages for forms
 type errors map[string][]string
 
@@ -15,3 +17,18 @@ func (e errors) Get(field string) string {
 	}
 	return es[0]
 }
+
+// Add error messages for the i-th value of a repeated field
+func (e errors) AddAt(field string, i int, message string) {
+	e.Add(indexed(field, i), message)
+}
+
+// Return the first error message of the i-th value of a repeated field
+func (e errors) GetAt(field string, i int) string {
+	return e.Get(indexed(field, i))
+}
+
+// Return the key of errors of the i-th value of a repeated field
+func indexed(field string, i int) string {
+	return fmt.Sprintf("%s.%d", field, i)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 )
@@ -12,6 +13,28 @@ import (
 // for sanity checking the format of an email address.
 var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
+// Parse a pattern and compile a regular expression for checking a single tag:
+// lowercase letters, digits and a few separators like in "c++" or "node.js".
+var TagRX = regexp.MustCompile(`^[\p{Ll}\p{N}][\p{Ll}\p{N}+#._-]*$`)
+
+// Split a comma separated list of tags, dropping blank and repeated ones.
+// Tags are lowercased and trimmed.
+func SplitTags(value string) []string {
+	var tags []string
+	seen := map[string]bool{}
+
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
 // Embeds a url.Values object (to hold the form data)
 // and an Errors field to hold any validation errors
 type Form struct {
@@ -85,6 +108,132 @@ func (f *Form) PermittedValues(field string, opts...string) {
 	f.Errors.Add(field, "This field is invalid")
 }
 
+// Check that a specific field in the form contains an integer between min
+// and max inclusive
+func (f *Form) IntRange(field string, min, max int) {
+	value := f.Get(field)
+	if value == "" {
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		f.Errors.Add(field, "This field must be a number")
+		return
+	}
+	if n < min || n > max {
+		f.Errors.Add(field, fmt.Sprintf("This field must be between %d and %d", min, max))
+	}
+}
+
+// Check that a specific field in the form contains a comma separated list
+// of at most max tags, every one matching TagRX and not longer than d characters
+func (f *Form) ValidTags(field string, max, d int) {
+	tags := SplitTags(f.Get(field))
+	if len(tags) > max {
+		f.Errors.Add(field, fmt.Sprintf("Too many tags (maximum is %d)", max))
+		return
+	}
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > d {
+			f.Errors.Add(field, fmt.Sprintf("Tag %q is too long (maximum is %d)", tag, d))
+			return
+		}
+		if !TagRX.MatchString(tag) {
+			f.Errors.Add(field, fmt.Sprintf("Tag %q is invalid", tag))
+			return
+		}
+	}
+}
+
+// Parse a pattern and compile a regular expression for checking a file name
+// of a multi-file snippet: no paths and no hidden files.
+var FilenameRX = regexp.MustCompile(`^[\p{L}\p{N}_-][\p{L}\p{N}_.-]*$`)
+
+// Return the i-th value of a repeated field or "" if there is none
+func (f *Form) GetAt(field string, i int) string {
+	values := f.Values[field]
+	if i < 0 || i >= len(values) {
+		return ""
+	}
+	return values[i]
+}
+
+// Return the number of entries made of the given repeated fields, which is
+// the largest number of values any of them has
+func (f *Form) Count(fields ...string) int {
+	n := 0
+	for _, field := range fields {
+		if len(f.Values[field]) > n {
+			n = len(f.Values[field])
+		}
+	}
+	return n
+}
+
+// Return true if the i-th values of all the repeated fields are blank
+func (f *Form) BlankAt(i int, fields ...string) bool {
+	for _, field := range fields {
+		if strings.TrimSpace(f.GetAt(field, i)) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Check that the i-th values of the repeated fields are present and not blank
+func (f *Form) RequiredAt(i int, fields ...string) {
+	for _, field := range fields {
+		if strings.TrimSpace(f.GetAt(field, i)) == "" {
+			f.Errors.AddAt(field, i, "This field cannot be blank")
+		}
+	}
+}
+
+// Check that the i-th value of a repeated field contains a maximum number of
+// characters
+func (f *Form) MaxLengthAt(field string, i, d int) {
+	if utf8.RuneCountInString(f.GetAt(field, i)) > d {
+		f.Errors.AddAt(field, i, fmt.Sprintf("This field is too long (maximum is %d)", d))
+	}
+}
+
+// Check that the i-th value of a repeated field matches a regular expression
+func (f *Form) MatchesPatternAt(field string, i int, pattern *regexp.Regexp) {
+	value := f.GetAt(field, i)
+	if value == "" {
+		return
+	}
+	if !pattern.MatchString(value) {
+		f.Errors.AddAt(field, i, "This field is invalid")
+	}
+}
+
+// Check that the i-th value of a repeated field matches one of the permitted
+// values
+func (f *Form) PermittedValuesAt(field string, i int, opts ...string) {
+	value := f.GetAt(field, i)
+	for _, opt := range opts {
+		if value == opt {
+			return
+		}
+	}
+	f.Errors.AddAt(field, i, "This field is invalid")
+}
+
+// Check that no non-blank value of a repeated field occurs twice
+func (f *Form) DistinctAt(field string) {
+	seen := map[string]bool{}
+	for i, value := range f.Values[field] {
+		if value == "" {
+			continue
+		}
+		if seen[value] {
+			f.Errors.AddAt(field, i, fmt.Sprintf("%q is used twice", value))
+		}
+		seen[value] = true
+	}
+}
+
 // Returns true if there are no errors
 func (f *Form) Valid() bool {
 	return len(f.Errors) == 0
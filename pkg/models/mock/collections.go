@@ -0,0 +1,96 @@
+package mock
+
+import (
+	"time"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+// Collection of mockUser with a snippet of their own and two of another
+// user, one of them unlisted and not counted in the size
+var mockCollection = &models.Collection{
+	ID:          1,
+	UserID:      1,
+	UserName:    "Alex",
+	Title:       "Haiku",
+	Description: "Poems about ponds and winds",
+	Created:     time.Now(),
+	Size:        2,
+}
+
+// Empty collection of another user than mockUser
+var mockForeignCollection = &models.Collection{
+	ID:       2,
+	UserID:   2,
+	UserName: "Bob",
+	Title:    "Bob's picks",
+	Created:  time.Now(),
+}
+
+type CollectionModel struct{}
+
+// Rewrite all mysql.CollectionModel methods
+
+func (m *CollectionModel) Insert(c *models.Collection) (int, error) {
+	return 3, nil
+}
+
+func (m *CollectionModel) Get(id int) (*models.Collection, error) {
+	switch id {
+	case 1:
+		return mockCollection, nil
+	case 2:
+		return mockForeignCollection, nil
+	default:
+		return nil, models.ErrNoRecord
+	}
+}
+
+func (m *CollectionModel) ByUser(userID int) ([]*models.Collection, error) {
+	switch userID {
+	case 1:
+		return []*models.Collection{mockCollection}, nil
+	case 2:
+		return []*models.Collection{mockForeignCollection}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *CollectionModel) Update(c *models.Collection) error {
+	return nil
+}
+
+func (m *CollectionModel) Delete(id int) error {
+	return nil
+}
+
+func (m *CollectionModel) Snippets(id int) ([]*models.Snippet, error) {
+	switch id {
+	case 1:
+		return []*models.Snippet{mockForeignSnippet, mockSnippet, mockUnlistedSnippet}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *CollectionModel) Add(id, snippetID int) error {
+	return nil
+}
+
+func (m *CollectionModel) Remove(id, snippetID int) error {
+	return nil
+}
+
+func (m *CollectionModel) Move(id, snippetID int, up bool) error {
+	if id != mockCollection.ID {
+		return models.ErrNoRecord
+	}
+
+	switch snippetID {
+	case mockSnippet.ID, mockForeignSnippet.ID, mockUnlistedSnippet.ID:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
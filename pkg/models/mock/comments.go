@@ -0,0 +1,69 @@
+package mock
+
+import (
+	"time"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+// Reply of another user than mockUser to mockComment
+var mockReply = &models.Comment{
+	ID:        2,
+	SnippetID: 1,
+	ParentID:  1,
+	UserID:    2,
+	UserName:  "Bob",
+	Content:   "Plop!",
+	Created:   time.Now(),
+}
+
+// Comment of mockUser on mockSnippet
+var mockComment = &models.Comment{
+	ID:        1,
+	SnippetID: 1,
+	UserID:    1,
+	UserName:  "Alex",
+	Content:   "A frog jumps in",
+	Created:   time.Now(),
+	Replies:   []*models.Comment{mockReply},
+}
+
+type CommentModel struct{}
+
+// Rewrite all mysql.CommentModel methods
+
+func (m *CommentModel) Insert(c *models.Comment) (int, error) {
+	// Only top-level comments can be replied to
+	if c.ParentID > 0 && (c.ParentID != mockComment.ID || c.SnippetID != mockComment.SnippetID) {
+		return 0, models.ErrNoRecord
+	}
+	return 3, nil
+}
+
+func (m *CommentModel) Get(id int) (*models.Comment, error) {
+	switch id {
+	case 1:
+		return mockComment, nil
+	case 2:
+		return mockReply, nil
+	default:
+		return nil, models.ErrNoRecord
+	}
+}
+
+func (m *CommentModel) BySnippet(snippetID int) ([]*models.Comment, error) {
+	switch snippetID {
+	case 1:
+		return []*models.Comment{mockComment}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *CommentModel) Update(id int, content string) error {
+	return nil
+}
+
+func (m *CommentModel) Delete(id int) error {
+	return nil
+}
@@ -2,30 +2,176 @@ package mock
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/alekslesik/snippetbox.learn/pkg/models"
 )
 
 var mockSnippet = &models.Snippet{
-	ID:      1,
-	Title:   "An old silent pond",
-	Content: "An old silent pond...",
-	Created: time.Now(),
-	Expires: time.Now(),
+	ID:         1,
+	UserID:     1,
+	UserName:   "Alex",
+	Title:      "An old silent pond",
+	Content:    "An old silent pond...",
+	Language:   "plaintext",
+	Visibility: models.Public,
+	Created:    time.Now(),
+	Expires:    time.Now(),
+	Tags:       []string{"basho", "haiku"},
+	Forks:      1,
+	Stars:      2,
+	TotalViews: 3,
+}
+
+// Multi-file fork of mockSnippet owned by another user than mockUser
+var mockForeignSnippet = &models.Snippet{
+	ID:         3,
+	UserID:     2,
+	UserName:   "Bob",
+	Title:      "Over the wintry forest",
+	Content:    "Over the wintry forest...\nwinds howl in rage\nwith no leaves to blow.",
+	Visibility: models.Public,
+	Created:    time.Now(),
+	Expires:    time.Now(),
+	Files: []*models.File{
+		{Name: "Dockerfile", Language: "docker", Content: "FROM alpine"},
+		{Name: "entrypoint.sh", Language: "bash", Content: "echo winds howl"},
+	},
+	ForkedFrom: 1,
+	Stars:      1,
+}
+
+// Unlisted snippet of another user than mockUser
+var mockUnlistedSnippet = &models.Snippet{
+	ID:         5,
+	UserID:     2,
+	UserName:   "Bob",
+	Title:      "Lightning flash",
+	Content:    "Lightning flash...",
+	Visibility: models.Unlisted,
+	Slug:       "3q2-7wAAAAAAAAAAAAAAAA",
+	Created:    time.Now(),
+	Expires:    time.Now(),
+}
+
+// Passphrase of mockProtectedSnippet
+const mockPassphrase = "open sesame"
+
+// Snippet of another user than mockUser protected by mockPassphrase
+var mockProtectedSnippet = &models.Snippet{
+	ID:         6,
+	UserID:     2,
+	UserName:   "Bob",
+	Title:      "A world of dew",
+	Content:    "A world of dew...",
+	Visibility: models.Public,
+	Protected:  true,
+	Created:    time.Now(),
+	Expires:    time.Now(),
+}
+
+// Snippet of another user than mockUser which expires after its first view
+var mockBurnSnippet = &models.Snippet{
+	ID:         7,
+	UserID:     2,
+	UserName:   "Bob",
+	Title:      "The crow has flown away",
+	Content:    "The crow has flown away...",
+	Visibility: models.Public,
+	MaxViews:   1,
+	Created:    time.Now(),
+	Expires:    time.Now(),
+}
+
+// Snippet of mockUser moved to the trash
+var mockDeletedSnippet = &models.Snippet{
+	ID:         4,
+	UserID:     1,
+	UserName:   "Alex",
+	Title:      "First autumn morning",
+	Content:    "First autumn morning...",
+	Visibility: models.Public,
+	Created:    time.Now(),
+	Expires:    time.Now(),
+	Deleted:    time.Now(),
+}
+
+// History of mockSnippet
+var mockRevisions = []*models.Revision{
+	{
+		ID:        1,
+		SnippetID: 1,
+		Number:    1,
+		UserID:    1,
+		UserName:  "Alex",
+		Title:     "An old pond",
+		Content:   "An old pond\nA frog jumps in",
+		Language:  "plaintext",
+		Created:   time.Now(),
+	},
+	{
+		ID:        2,
+		SnippetID: 1,
+		Number:    2,
+		UserID:    1,
+		UserName:  "Alex",
+		Title:     "An old silent pond",
+		Content:   "An old silent pond...",
+		Language:  "plaintext",
+		Created:   time.Now(),
+	},
 }
 
 type SnippetModel struct{}
 
 // Rewrite all mysql.SnippetModel methods
-func (m *SnippetModel) Insert(title, content, expires string) (int, error) {
+func (m *SnippetModel) Insert(s *models.Snippet) (int, error) {
 	return 2, nil
 }
 
+func (m *SnippetModel) Update(s *models.Snippet) error {
+	switch s.ID {
+	case 1, 3:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) Extend(id int, expires time.Time) error {
+	switch id {
+	case 1, 3:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
 func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
+	s, err := m.Peek(id)
+	if err != nil || s.MaxViews == 0 {
+		return s, err
+	}
+
+	// Count the view on a copy, the mock snippets are shared between tests
+	viewed := *s
+	viewed.Views++
+	return &viewed, nil
+}
+
+func (m *SnippetModel) Peek(id int) (*models.Snippet, error) {
 	switch id {
 	case 1:
 		return mockSnippet, nil
+	case 3:
+		return mockForeignSnippet, nil
+	case 5:
+		return mockUnlistedSnippet, nil
+	case 6:
+		return mockProtectedSnippet, nil
+	case 7:
+		return mockBurnSnippet, nil
 	case 100 :
 		return nil, models.ErrDuplicateEmail
 	default:
@@ -33,21 +179,273 @@ func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
 	}
 }
 
+func (m *SnippetModel) GetBySlug(slug string) (*models.Snippet, error) {
+	switch slug {
+	case mockUnlistedSnippet.Slug:
+		return mockUnlistedSnippet, nil
+	default:
+		return nil, models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) Unlock(id int, passphrase string) error {
+	switch {
+	case id != mockProtectedSnippet.ID:
+		return models.ErrNoRecord
+	case passphrase != mockPassphrase:
+		return models.ErrInvalidCredentials
+	default:
+		return nil
+	}
+}
+
 func (m *SnippetModel) Latest() ([]*models.Snippet, error) {
 	return []*models.Snippet{mockSnippet}, nil
 }
 
+// Total number of snippets reported by the mock archive
+const mockArchiveTotal = 12
+
+func (m *SnippetModel) Archive(limit, offset int) ([]*models.Snippet, int, error) {
+	if offset >= mockArchiveTotal {
+		return nil, mockArchiveTotal, nil
+	}
+	return []*models.Snippet{mockSnippet}, mockArchiveTotal, nil
+}
+
+func (m *SnippetModel) Search(query string, limit, offset int) ([]*models.Snippet, int, error) {
+	var found []*models.Snippet
+	for _, s := range []*models.Snippet{mockSnippet, mockForeignSnippet, mockUnlistedSnippet, mockProtectedSnippet, mockBurnSnippet} {
+		// Search shows the content, so like the database it skips unlisted,
+		// protected and view-limited snippets
+		if s.Unlisted() || s.Protected || s.MaxViews > 0 {
+			continue
+		}
+		if strings.Contains(strings.ToLower(s.Content), strings.ToLower(query)) {
+			found = append(found, s)
+		}
+	}
+	if offset > 0 {
+		return nil, len(found), nil
+	}
+	return found, len(found), nil
+}
+
+func (m *SnippetModel) ByTag(tag string, limit, offset int) ([]*models.Snippet, int, error) {
+	for _, t := range mockSnippet.Tags {
+		if offset == 0 && t == tag {
+			return []*models.Snippet{mockSnippet}, 1, nil
+		}
+	}
+	return nil, 0, nil
+}
+
+func (m *SnippetModel) TagCloud() ([]*models.Tag, error) {
+	return []*models.Tag{{Name: "basho", Count: 1}, {Name: "haiku", Count: 3}}, nil
+}
+
+func (m *SnippetModel) ByUser(userID int) ([]*models.Snippet, error) {
+	switch userID {
+	case 1:
+		return []*models.Snippet{mockSnippet}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *SnippetModel) Delete(id int) error {
+	switch id {
+	case 1, 3:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) Restore(id, userID int) error {
+	switch {
+	case id == mockDeletedSnippet.ID && userID == mockDeletedSnippet.UserID:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) Trash(userID int) ([]*models.Snippet, error) {
+	switch userID {
+	case 1:
+		return []*models.Snippet{mockDeletedSnippet}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *SnippetModel) Purge(retention time.Duration) (int, error) {
+	return 0, nil
+}
+
+func (m *SnippetModel) DeleteExpired(limit int) (int, error) {
+	return 0, nil
+}
+
+func (m *SnippetModel) Revisions(snippetID int) ([]*models.Revision, error) {
+	switch snippetID {
+	case 1:
+		return mockRevisions, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *SnippetModel) Revision(snippetID, number int) (*models.Revision, error) {
+	if snippetID == 1 && number >= 1 && number <= len(mockRevisions) {
+		return mockRevisions[number-1], nil
+	}
+	return nil, models.ErrNoRecord
+}
+
+// mockUser starred mockForeignSnippet
+func (m *SnippetModel) Star(id, userID int, star bool) error {
+	return nil
+}
+
+func (m *SnippetModel) IsStarred(id, userID int) (bool, error) {
+	return id == 3 && userID == 1, nil
+}
+
+// mockUser also starred mockUnlistedSnippet before it was made unlisted,
+// which is left out like the database does
+func (m *SnippetModel) Starred(userID int) ([]*models.Snippet, error) {
+	if userID != 1 {
+		return nil, nil
+	}
+
+	var starred []*models.Snippet
+	for _, s := range []*models.Snippet{mockForeignSnippet, mockUnlistedSnippet} {
+		if !s.Unlisted() || s.UserID == userID {
+			starred = append(starred, s)
+		}
+	}
+	return starred, nil
+}
+
+func (m *SnippetModel) RecordViews(views []*models.ViewCount) error {
+	return nil
+}
+
+// mockSnippet was viewed today and two days ago
+func (m *SnippetModel) DailyViews(id int, since time.Time) ([]*models.ViewCount, error) {
+	switch id {
+	case 1:
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		return []*models.ViewCount{
+			{SnippetID: 1, Day: today.AddDate(0, 0, -2), Views: 1},
+			{SnippetID: 1, Day: today, Views: 2},
+		}, nil
+	default:
+		return nil, nil
+	}
+}
+
 type SnippetModelERR struct{}
 
 // Rewrite all mysql.SnippetModel methods, return errors
-func (m *SnippetModelERR) Insert(title, content, expires string) (int, error) {
+func (m *SnippetModelERR) Insert(s *models.Snippet) (int, error) {
 	return 0, errors.New("test error Insert()")
 }
 
+func (m *SnippetModelERR) Update(s *models.Snippet) error {
+	return errors.New("test error Update()")
+}
+
+func (m *SnippetModelERR) Extend(id int, expires time.Time) error {
+	return errors.New("test error Extend()")
+}
+
 func (m *SnippetModelERR) Get(id int) (*models.Snippet, error) {
 	return &models.Snippet{}, errors.New("test error Get()")
 }
 
+func (m *SnippetModelERR) Peek(id int) (*models.Snippet, error) {
+	return &models.Snippet{}, errors.New("test error Peek()")
+}
+
+func (m *SnippetModelERR) GetBySlug(slug string) (*models.Snippet, error) {
+	return &models.Snippet{}, errors.New("test error GetBySlug()")
+}
+
+func (m *SnippetModelERR) Unlock(id int, passphrase string) error {
+	return errors.New("test error Unlock()")
+}
+
 func (m *SnippetModelERR) Latest() ([]*models.Snippet, error) {
 	return []*models.Snippet{}, errors.New("test error Latest()")
-}
\ No newline at end of file
+}
+
+func (m *SnippetModelERR) Archive(limit, offset int) ([]*models.Snippet, int, error) {
+	return []*models.Snippet{}, 0, errors.New("test error Archive()")
+}
+
+func (m *SnippetModelERR) Search(query string, limit, offset int) ([]*models.Snippet, int, error) {
+	return []*models.Snippet{}, 0, errors.New("test error Search()")
+}
+
+func (m *SnippetModelERR) ByTag(tag string, limit, offset int) ([]*models.Snippet, int, error) {
+	return []*models.Snippet{}, 0, errors.New("test error ByTag()")
+}
+
+func (m *SnippetModelERR) TagCloud() ([]*models.Tag, error) {
+	return []*models.Tag{}, errors.New("test error TagCloud()")
+}
+
+func (m *SnippetModelERR) ByUser(userID int) ([]*models.Snippet, error) {
+	return []*models.Snippet{}, errors.New("test error ByUser()")
+}
+
+func (m *SnippetModelERR) Delete(id int) error {
+	return errors.New("test error Delete()")
+}
+
+func (m *SnippetModelERR) Restore(id, userID int) error {
+	return errors.New("test error Restore()")
+}
+
+func (m *SnippetModelERR) Trash(userID int) ([]*models.Snippet, error) {
+	return []*models.Snippet{}, errors.New("test error Trash()")
+}
+
+func (m *SnippetModelERR) Purge(retention time.Duration) (int, error) {
+	return 0, errors.New("test error Purge()")
+}
+
+func (m *SnippetModelERR) DeleteExpired(limit int) (int, error) {
+	return 0, errors.New("test error DeleteExpired()")
+}
+
+func (m *SnippetModelERR) Revisions(snippetID int) ([]*models.Revision, error) {
+	return []*models.Revision{}, errors.New("test error Revisions()")
+}
+
+func (m *SnippetModelERR) Revision(snippetID, number int) (*models.Revision, error) {
+	return &models.Revision{}, errors.New("test error Revision()")
+}
+
+func (m *SnippetModelERR) Star(id, userID int, star bool) error {
+	return errors.New("test error Star()")
+}
+
+func (m *SnippetModelERR) IsStarred(id, userID int) (bool, error) {
+	return false, errors.New("test error IsStarred()")
+}
+
+func (m *SnippetModelERR) Starred(userID int) ([]*models.Snippet, error) {
+	return []*models.Snippet{}, errors.New("test error Starred()")
+}
+
+func (m *SnippetModelERR) RecordViews(views []*models.ViewCount) error {
+	return errors.New("test error RecordViews()")
+}
+
+func (m *SnippetModelERR) DailyViews(id int, since time.Time) ([]*models.ViewCount, error) {
+	return []*models.ViewCount{}, errors.New("test error DailyViews()")
+}
@@ -13,12 +13,129 @@ var (
 	ErrDuplicateEmail = errors.New("models: duplicate email")
 )
 
+// Visibility of snippets. Public snippets are listed everywhere and
+// reachable by ID, unlisted ones only by their random slug.
+const (
+	Public   = "public"
+	Unlisted = "unlisted"
+)
+
+// Expiry time of snippets which never expire, the latest one a MySQL
+// DATETIME can hold
+var Never = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
 type Snippet struct {
-	ID      int
-	Title   string
-	Content string
-	Created time.Time
-	Expires time.Time
+	ID         int
+	UserID     int
+	UserName   string
+	Title      string
+	Content    string
+	Language   string
+	Visibility string
+	Slug       string
+	Created    time.Time
+	Expires    time.Time
+	Deleted    time.Time
+	Tags       []string
+	// Plain-text passphrase set by the author on create. It is stored only
+	// as a hash and never read back, Protected reports whether it is set.
+	Passphrase string
+	Protected  bool
+	// The snippet expires once it was viewed MaxViews times, 0 means no limit
+	Views    int
+	MaxViews int
+	// Further files shared along with the snippet content
+	Files []*File
+	// ID of the snippet this one is a fork of, 0 if it's an original, and
+	// the number of forks of this snippet
+	ForkedFrom int
+	Forks      int
+	// Number of users who starred the snippet
+	Stars int
+	// Views recorded for the snippet analytics, unlike Views not only counted
+	// for snippets with MaxViews
+	TotalViews int
+}
+
+// Return the number of views left before the snippet expires or -1 if its
+// views are not limited
+func (s *Snippet) ViewsLeft() int {
+	if s.MaxViews == 0 {
+		return -1
+	}
+
+	return s.MaxViews - s.Views
+}
+
+// Return true if the snippet never expires
+func (s *Snippet) NeverExpires() bool {
+	return !s.Expires.Before(Never)
+}
+
+// Return true if the snippet is reachable only by its slug
+func (s *Snippet) Unlisted() bool {
+	return s.Visibility == Unlisted
+}
+
+// File of a multi-file snippet
+type File struct {
+	Name     string
+	Language string
+	Content  string
+}
+
+// Stored version of a snippet. Number is the 1-based position of the
+// revision in the snippet history.
+type Revision struct {
+	ID        int
+	SnippetID int
+	Number    int
+	UserID    int
+	UserName  string
+	Title     string
+	Content   string
+	Language  string
+	Created   time.Time
+}
+
+// Comment on a snippet. Replies have the ID of the top-level comment they
+// answer as ParentID, top-level comments carry their replies. Updated is zero
+// if the comment was never edited.
+type Comment struct {
+	ID        int
+	SnippetID int
+	ParentID  int
+	UserID    int
+	UserName  string
+	Content   string
+	Created   time.Time
+	Updated   time.Time
+	Replies   []*Comment
+}
+
+// Views of a snippet on one UTC day
+type ViewCount struct {
+	SnippetID int
+	Day       time.Time
+	Views     int
+}
+
+// Named list of snippets put together by a user
+type Collection struct {
+	ID          int
+	UserID      int
+	UserName    string
+	Title       string
+	Description string
+	Created     time.Time
+	// Number of not expired public snippets in the collection
+	Size int
+}
+
+// Tag with the number of snippets marked by it
+type Tag struct {
+	Name  string
+	Count int
 }
 
 type User struct {
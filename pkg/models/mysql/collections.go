@@ -0,0 +1,206 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+type CollectionModel struct {
+	DB *sql.DB
+}
+
+// Columns scanned by scanCollection, c is the collections table and u the
+// users table of the owner. The size counts the snippets everyone sees on
+// the collection page, unlisted ones are only listed for their authors.
+const collectionColumns = `c.id, c.user_id, u.name, c.title, c.description, c.created,
+    (SELECT COUNT(*) FROM collection_snippets cs INNER JOIN snippets s ON s.id = cs.snippet_id
+    WHERE cs.collection_id = c.id AND s.expires > UTC_TIMESTAMP() AND s.deleted IS NULL
+    AND s.visibility = 'public')`
+
+func scanCollection(row scanner) (*models.Collection, error) {
+	c := &models.Collection{}
+
+	err := row.Scan(&c.ID, &c.UserID, &c.UserName, &c.Title, &c.Description, &c.Created, &c.Size)
+	return c, err
+}
+
+// Insert a new empty collection and return its ID
+func (m *CollectionModel) Insert(c *models.Collection) (int, error) {
+	stmt := `INSERT INTO collections (user_id, title, description, created)
+    VALUES(?, ?, ?, UTC_TIMESTAMP())`
+
+	result, err := m.DB.Exec(stmt, c.UserID, c.Title, c.Description)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// Return the collection with the given ID
+func (m *CollectionModel) Get(id int) (*models.Collection, error) {
+	stmt := `SELECT ` + collectionColumns + `
+    FROM collections c INNER JOIN users u ON u.id = c.user_id
+    WHERE c.id = ?`
+
+	c, err := scanCollection(m.DB.QueryRow(stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Return the collections of the user ordered by title
+func (m *CollectionModel) ByUser(userID int) ([]*models.Collection, error) {
+	stmt := `SELECT ` + collectionColumns + `
+    FROM collections c INNER JOIN users u ON u.id = c.user_id
+    WHERE c.user_id = ?
+    ORDER BY c.title, c.id`
+
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var collections []*models.Collection
+
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
+
+// Change the title and description of the collection
+func (m *CollectionModel) Update(c *models.Collection) error {
+	stmt := `UPDATE collections SET title = ?, description = ? WHERE id = ?`
+
+	_, err := m.DB.Exec(stmt, c.Title, c.Description, c.ID)
+	return err
+}
+
+// Delete the collection, its snippets stay untouched
+func (m *CollectionModel) Delete(id int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM collection_snippets WHERE collection_id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM collections WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Return the not expired snippets of the collection in their order
+func (m *CollectionModel) Snippets(id int) ([]*models.Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + `
+    FROM collection_snippets cs INNER JOIN snippets s ON s.id = cs.snippet_id
+    INNER JOIN users u ON u.id = s.user_id
+    WHERE s.expires > UTC_TIMESTAMP() AND s.deleted IS NULL AND cs.collection_id = ?
+    ORDER BY cs.position`
+
+	snippets := &SnippetModel{DB: m.DB}
+	return snippets.query(stmt, id)
+}
+
+// Append the snippet to the end of the collection. Adding a snippet the
+// collection already has is not an error and keeps its position.
+func (m *CollectionModel) Add(id, snippetID int) error {
+	stmt := `INSERT IGNORE INTO collection_snippets (collection_id, snippet_id, position)
+    SELECT ?, ?, COALESCE(MAX(position), 0) + 1 FROM collection_snippets WHERE collection_id = ?`
+
+	_, err := m.DB.Exec(stmt, id, snippetID, id)
+	return err
+}
+
+// Remove the snippet from the collection
+func (m *CollectionModel) Remove(id, snippetID int) error {
+	stmt := `DELETE FROM collection_snippets WHERE collection_id = ? AND snippet_id = ?`
+
+	_, err := m.DB.Exec(stmt, id, snippetID)
+	return err
+}
+
+// Swap the snippet with the previous one of the collection if up is true or
+// with the next one otherwise. Expired and deleted snippets, which aren't
+// listed, are skipped. Moving the first snippet up or the last one down
+// changes nothing. If the collection doesn't have the snippet,
+// models.ErrNoRecord is returned.
+func (m *CollectionModel) Move(id, snippetID int, up bool) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var position int
+	stmt := `SELECT position FROM collection_snippets
+    WHERE collection_id = ? AND snippet_id = ? FOR UPDATE`
+	err = tx.QueryRow(stmt, id, snippetID).Scan(&position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrNoRecord
+	} else if err != nil {
+		return err
+	}
+
+	stmt = `SELECT cs.snippet_id, cs.position
+    FROM collection_snippets cs INNER JOIN snippets s ON s.id = cs.snippet_id
+    WHERE s.expires > UTC_TIMESTAMP() AND s.deleted IS NULL AND cs.collection_id = ? AND cs.position > ?
+    ORDER BY cs.position LIMIT 1 FOR UPDATE`
+	if up {
+		stmt = `SELECT cs.snippet_id, cs.position
+    FROM collection_snippets cs INNER JOIN snippets s ON s.id = cs.snippet_id
+    WHERE s.expires > UTC_TIMESTAMP() AND s.deleted IS NULL AND cs.collection_id = ? AND cs.position < ?
+    ORDER BY cs.position DESC LIMIT 1 FOR UPDATE`
+	}
+
+	var otherID, otherPosition int
+	err = tx.QueryRow(stmt, id, position).Scan(&otherID, &otherPosition)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	stmt = `UPDATE collection_snippets SET position = ? WHERE collection_id = ? AND snippet_id = ?`
+	_, err = tx.Exec(stmt, otherPosition, id, snippetID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(stmt, position, id, otherID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,139 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+func TestCollectionModelOrder(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	db, teardown := newTestDB(t)
+	defer teardown()
+
+	snippets := SnippetModel{db}
+	m := CollectionModel{db}
+
+	// Three snippets of Alice from setup.sql
+	var ids []int
+	for _, title := range []string{"Spring", "Summer", "Autumn"} {
+		id, err := snippets.Insert(&models.Snippet{
+			UserID:     1,
+			Title:      title,
+			Content:    title,
+			Language:   "plaintext",
+			Visibility: models.Public,
+			Expires:    models.Never,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	id, err := m.Insert(&models.Collection{UserID: 1, Title: "Seasons"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Adding a snippet twice keeps its first position
+	for _, snippetID := range append(ids, ids[0]) {
+		err = m.Add(id, snippetID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Spring, Summer, Autumn -> Summer, Spring, Autumn -> Summer, Autumn, Spring
+	err = m.Move(id, ids[1], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Move(id, ids[0], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first snippet can't move up
+	err = m.Move(id, ids[1], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Move(id, 100, true)
+	if err != models.ErrNoRecord {
+		t.Errorf("want %v; got %v", models.ErrNoRecord, err)
+	}
+
+	want := []int{ids[1], ids[2], ids[0]}
+	got, err := m.Snippets(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %d snippets; got %d", len(want), len(got))
+	}
+	for i, s := range got {
+		if s.ID != want[i] {
+			t.Errorf("position %d: want snippet %d; got %d", i+1, want[i], s.ID)
+		}
+	}
+
+	// Summer, [Autumn], Spring -> Spring, [Autumn], Summer skipping the
+	// trashed snippet
+	err = snippets.Delete(ids[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Move(id, ids[0], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []int{ids[0], ids[1]}
+	got, err = m.Snippets(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) || got[0].ID != want[0] || got[1].ID != want[1] {
+		t.Errorf("want snippets %v; got %v", want, got)
+	}
+
+	// Unlisted snippets aren't counted, like they aren't listed for others
+	unlistedID, err := snippets.Insert(&models.Snippet{
+		UserID:     1,
+		Title:      "Winter",
+		Content:    "Winter",
+		Language:   "plaintext",
+		Visibility: models.Unlisted,
+		Expires:    models.Never,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Add(id, unlistedID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Remove(id, ids[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := m.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Size != 2 || c.UserName != "Alice Jones" {
+		t.Errorf("want 2 snippets of Alice Jones; got %v", c)
+	}
+
+	err = m.Delete(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Get(id)
+	if err != models.ErrNoRecord {
+		t.Errorf("want %v; got %v", models.ErrNoRecord, err)
+	}
+}
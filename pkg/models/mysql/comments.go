@@ -0,0 +1,141 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+type CommentModel struct {
+	DB *sql.DB
+}
+
+// Columns scanned by scanComment, c is the comments table and u the users
+// table of the author
+const commentColumns = `c.id, c.snippet_id, COALESCE(c.parent_id, 0), c.user_id, u.name, c.content,
+    c.created, c.updated`
+
+// Scan a row of commentColumns, implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanComment(row scanner) (*models.Comment, error) {
+	c := &models.Comment{}
+	var updated sql.NullTime
+
+	err := row.Scan(&c.ID, &c.SnippetID, &c.ParentID, &c.UserID, &c.UserName, &c.Content,
+		&c.Created, &updated)
+	c.Updated = updated.Time
+
+	return c, err
+}
+
+// Insert a new comment and return its ID. A reply must answer a top-level
+// comment of the same snippet, otherwise models.ErrNoRecord is returned.
+func (m *CommentModel) Insert(c *models.Comment) (int, error) {
+	parentID := sql.NullInt64{Int64: int64(c.ParentID), Valid: c.ParentID > 0}
+
+	if parentID.Valid {
+		var ok bool
+		stmt := `SELECT EXISTS(SELECT true FROM comments
+        WHERE id = ? AND snippet_id = ? AND parent_id IS NULL)`
+		err := m.DB.QueryRow(stmt, c.ParentID, c.SnippetID).Scan(&ok)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return 0, models.ErrNoRecord
+		}
+	}
+
+	stmt := `INSERT INTO comments (snippet_id, parent_id, user_id, content, created)
+    VALUES(?, ?, ?, ?, UTC_TIMESTAMP())`
+
+	result, err := m.DB.Exec(stmt, c.SnippetID, parentID, c.UserID, c.Content)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// Return the comment with the given ID
+func (m *CommentModel) Get(id int) (*models.Comment, error) {
+	stmt := `SELECT ` + commentColumns + `
+    FROM comments c INNER JOIN users u ON u.id = c.user_id
+    WHERE c.id = ?`
+
+	c, err := scanComment(m.DB.QueryRow(stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Return the top-level comments of the snippet from oldest to newest, each
+// with its replies in the same order
+func (m *CommentModel) BySnippet(snippetID int) ([]*models.Comment, error) {
+	stmt := `SELECT ` + commentColumns + `
+    FROM comments c INNER JOIN users u ON u.id = c.user_id
+    WHERE c.snippet_id = ?
+    ORDER BY c.created, c.id`
+
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var comments []*models.Comment
+	parents := map[int]*models.Comment{}
+
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		// Parents are older than their replies, so they are already known
+		if parent, ok := parents[c.ParentID]; ok {
+			parent.Replies = append(parent.Replies, c)
+			continue
+		}
+
+		parents[c.ID] = c
+		comments = append(comments, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// Change the content of the comment
+func (m *CommentModel) Update(id int, content string) error {
+	stmt := `UPDATE comments SET content = ?, updated = UTC_TIMESTAMP() WHERE id = ?`
+
+	_, err := m.DB.Exec(stmt, content, id)
+	return err
+}
+
+// Delete the comment together with its replies
+func (m *CommentModel) Delete(id int) error {
+	stmt := `DELETE FROM comments WHERE id = ? OR parent_id = ?`
+
+	_, err := m.DB.Exec(stmt, id, id)
+	return err
+}
@@ -0,0 +1,72 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+func TestCommentModelThreads(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	db, teardown := newTestDB(t)
+	defer teardown()
+
+	m := CommentModel{db}
+
+	// Alice from setup.sql comments on snippet 1 and replies to herself
+	id, err := m.Insert(&models.Comment{SnippetID: 1, UserID: 1, Content: "A frog jumps in"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replyID, err := m.Insert(&models.Comment{SnippetID: 1, ParentID: id, UserID: 1, Content: "Plop!"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only one level of replies, and only to comments of the same snippet
+	_, err = m.Insert(&models.Comment{SnippetID: 1, ParentID: replyID, UserID: 1, Content: "Splash!"})
+	if err != models.ErrNoRecord {
+		t.Errorf("reply to reply: want %v; got %v", models.ErrNoRecord, err)
+	}
+	_, err = m.Insert(&models.Comment{SnippetID: 2, ParentID: id, UserID: 1, Content: "Splash!"})
+	if err != models.ErrNoRecord {
+		t.Errorf("reply on other snippet: want %v; got %v", models.ErrNoRecord, err)
+	}
+
+	comments, err := m.BySnippet(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 1 || comments[0].ID != id || comments[0].UserName != "Alice Jones" {
+		t.Fatalf("want comment %d of Alice Jones; got %v", id, comments)
+	}
+	if len(comments[0].Replies) != 1 || comments[0].Replies[0].ID != replyID {
+		t.Errorf("want reply %d; got %v", replyID, comments[0].Replies)
+	}
+
+	// Edited comments have the update time
+	err = m.Update(replyID, "Splash!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := m.Get(replyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Content != "Splash!" || c.Updated.IsZero() {
+		t.Errorf("want edited reply; got %v", c)
+	}
+
+	// Deleting a comment removes its replies
+	err = m.Delete(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Get(replyID)
+	if err != models.ErrNoRecord {
+		t.Errorf("want %v; got %v", models.ErrNoRecord, err)
+	}
+}
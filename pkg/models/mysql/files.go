@@ -0,0 +1,52 @@
+package mysql
+
+import (
+	"database/sql"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+// Return files of the snippet with the given ID in the order they were added
+func (m *SnippetModel) files(id int) ([]*models.File, error) {
+	stmt := `SELECT filename, language, content FROM snippet_files
+    WHERE snippet_id = ? ORDER BY position`
+
+	rows, err := m.DB.Query(stmt, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var files []*models.File
+
+	for rows.Next() {
+		f := &models.File{}
+		err = rows.Scan(&f.Name, &f.Language, &f.Content)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// Add every file to the snippet with the given ID within the transaction
+func insertFiles(tx *sql.Tx, id int, files []*models.File) error {
+	stmt := `INSERT INTO snippet_files (snippet_id, position, filename, language, content)
+    VALUES(?, ?, ?, ?, ?)`
+
+	for i, f := range files {
+		_, err := tx.Exec(stmt, id, i, f.Name, f.Language, f.Content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
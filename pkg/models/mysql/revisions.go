@@ -0,0 +1,73 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+// Return all revisions of the snippet with the given ID from the oldest to
+// the newest
+func (m *SnippetModel) Revisions(snippetID int) ([]*models.Revision, error) {
+	stmt := `SELECT r.id, r.snippet_id, r.user_id, u.name, r.title, r.content, r.language, r.created
+    FROM snippet_revisions r INNER JOIN users u ON u.id = r.user_id
+    WHERE r.snippet_id = ? ORDER BY r.id`
+
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var revisions []*models.Revision
+
+	for rows.Next() {
+		r := &models.Revision{Number: len(revisions) + 1}
+		err = rows.Scan(&r.ID, &r.SnippetID, &r.UserID, &r.UserName, &r.Title, &r.Content, &r.Language, &r.Created)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// Return the revision of the snippet with the given ID by its number in the
+// snippet history
+func (m *SnippetModel) Revision(snippetID, number int) (*models.Revision, error) {
+	if number < 1 {
+		return nil, models.ErrNoRecord
+	}
+
+	stmt := `SELECT r.id, r.snippet_id, r.user_id, u.name, r.title, r.content, r.language, r.created
+    FROM snippet_revisions r INNER JOIN users u ON u.id = r.user_id
+    WHERE r.snippet_id = ? ORDER BY r.id LIMIT 1 OFFSET ?`
+
+	r := &models.Revision{Number: number}
+	err := m.DB.QueryRow(stmt, snippetID, number-1).Scan(&r.ID, &r.SnippetID, &r.UserID, &r.UserName, &r.Title, &r.Content, &r.Language, &r.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Store the current version of the snippet with the given ID within the
+// transaction. s.UserID is recorded as the author of the revision.
+func insertRevision(tx *sql.Tx, id int, s *models.Snippet) error {
+	stmt := `INSERT INTO snippet_revisions (snippet_id, user_id, title, content, language, created)
+    VALUES(?, ?, ?, ?, ?, UTC_TIMESTAMP())`
+
+	_, err := tx.Exec(stmt, id, s.UserID, s.Title, s.Content, s.Language)
+	return err
+}
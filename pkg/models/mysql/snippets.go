@@ -1,9 +1,15 @@
 package mysql
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
 	"errors"
+	"strings"
+	"time"
+
 	"github.com/alekslesik/snippetbox.learn/pkg/models"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Determine type which wrap connect pool sql.DB
@@ -11,14 +17,85 @@ type SnippetModel struct {
 	DB *sql.DB
 }
 
-// Create new snippet in database
-func (m *SnippetModel) Insert(title, content, expires string) (int, error) {
+// Columns of the snippets table (s) joined with the users table (u) which
+// are read into models.Snippet
+const snippetColumns = `s.id, s.user_id, u.name, s.title, s.content, s.language,
+    s.visibility, COALESCE(s.slug, ''), s.hashed_passphrase IS NOT NULL, s.views, COALESCE(s.max_views, 0),
+    COALESCE(s.forked_from, 0), (SELECT COUNT(*) FROM stars sc WHERE sc.snippet_id = s.id),
+    s.created, s.expires`
+
+// Return pointers to the models.Snippet fields in the order of snippetColumns
+func snippetFields(s *models.Snippet) []interface{} {
+	return []interface{}{&s.ID, &s.UserID, &s.UserName, &s.Title, &s.Content, &s.Language,
+		&s.Visibility, &s.Slug, &s.Protected, &s.Views, &s.MaxViews,
+		&s.ForkedFrom, &s.Stars, &s.Created, &s.Expires}
+}
+
+// Return a new random URL-safe slug for an unlisted snippet or NULL for a
+// public one
+func newSlug(visibility string) (sql.NullString, error) {
+	if visibility != models.Unlisted {
+		return sql.NullString{}, nil
+	}
+
+	// 128 bits of entropy can't be guessed or enumerated
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+
+	return sql.NullString{String: base64.RawURLEncoding.EncodeToString(b), Valid: true}, nil
+}
+
+// Return a bcrypt hash of the snippet passphrase or NULL if there is none
+func hashPassphrase(passphrase string) (sql.NullString, error) {
+	if passphrase == "" {
+		return sql.NullString{}, nil
+	}
+
+	hashedPassphrase, err := bcrypt.GenerateFromPassword([]byte(passphrase), 12)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+
+	return sql.NullString{String: string(hashedPassphrase), Valid: true}, nil
+}
+
+// Create new snippet with its tags and files in database. The snippet is owned by
+// the user with s.UserID and expires at s.Expires or after s.MaxViews views,
+// if set. If s.Passphrase is set, only its bcrypt hash is stored. Forks refer
+// to their original by s.ForkedFrom.
+func (m *SnippetModel) Insert(s *models.Snippet) (int, error) {
+	// The snippet and its tags are inserted in one transaction
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	slug, err := newSlug(s.Visibility)
+	if err != nil {
+		return 0, err
+	}
+
+	hashedPassphrase, err := hashPassphrase(s.Passphrase)
+	if err != nil {
+		return 0, err
+	}
+
+	// No limit of views and originals are stored as NULL
+	maxViews := sql.NullInt64{Int64: int64(s.MaxViews), Valid: s.MaxViews > 0}
+	forkedFrom := sql.NullInt64{Int64: int64(s.ForkedFrom), Valid: s.ForkedFrom > 0}
+
 	// SQL request we wanted to execute
-	stmt := `INSERT INTO snippets (title, content, created, expires)
-    VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+	stmt := `INSERT INTO snippets (user_id, title, content, language, visibility, slug, hashed_passphrase, max_views,
+    forked_from, created, expires)
+    VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, UTC_TIMESTAMP(), ?)`
 
 	// Use Exec() for execute SQL request
-	result, err := m.DB.Exec(stmt, title, content, expires)
+	result, err := tx.Exec(stmt, s.UserID, s.Title, s.Content, s.Language, s.Visibility, slug, hashedPassphrase,
+		maxViews, forkedFrom, s.Expires.UTC())
 	if err != nil {
 		return 0, err
 	}
@@ -29,23 +106,139 @@ func (m *SnippetModel) Insert(title, content, expires string) (int, error) {
 		return 0, err
 	}
 
-	return int(id), nil
+	err = insertTags(tx, int(id), s.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	err = insertFiles(tx, int(id), s.Files)
+	if err != nil {
+		return 0, err
+	}
+
+	// The first version starts the snippet history
+	err = insertRevision(tx, int(id), s)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), tx.Commit()
+}
+
+// Update title, content, language, visibility, tags and files of the snippet
+// with s.ID. An unlisted snippet keeps its slug, a snippet which becomes
+// unlisted gets a new one. The new version is added to the snippet history as
+// edited by s.UserID. Return ErrNoRecord if the snippet expired or was deleted.
+func (m *SnippetModel) Update(s *models.Snippet) error {
+	slug, err := newSlug(s.Visibility)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the snippet before its tags, files and history are touched. The
+	// rows affected by the UPDATE can't tell a gone snippet from an unchanged
+	// one, MySQL only counts changed rows.
+	var id int
+	stmt := `SELECT id FROM snippets
+    WHERE expires > UTC_TIMESTAMP() AND deleted IS NULL AND id = ? FOR UPDATE`
+	err = tx.QueryRow(stmt, s.ID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrNoRecord
+	} else if err != nil {
+		return err
+	}
+
+	stmt = `UPDATE snippets SET title = ?, content = ?, language = ?, visibility = ?,
+    slug = IF(? IS NULL, NULL, COALESCE(slug, ?))
+    WHERE id = ?`
+
+	_, err = tx.Exec(stmt, s.Title, s.Content, s.Language, s.Visibility, slug, slug, s.ID)
+	if err != nil {
+		return err
+	}
+
+	// Replace the old set of tags with the new one
+	_, err = tx.Exec(`DELETE FROM snippet_tags WHERE snippet_id = ?`, s.ID)
+	if err != nil {
+		return err
+	}
+
+	err = insertTags(tx, s.ID, s.Tags)
+	if err != nil {
+		return err
+	}
+
+	// Replace the files the same way
+	_, err = tx.Exec(`DELETE FROM snippet_files WHERE snippet_id = ?`, s.ID)
+	if err != nil {
+		return err
+	}
+
+	err = insertFiles(tx, s.ID, s.Files)
+	if err != nil {
+		return err
+	}
+
+	err = insertRevision(tx, s.ID, s)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// Return snippet data by ID
+// Move the expiry of the not expired snippet with the given ID to the given
+// time. Return ErrNoRecord if there is no such snippet.
+func (m *SnippetModel) Extend(id int, expires time.Time) error {
+	stmt := `UPDATE snippets SET expires = ?
+    WHERE expires > UTC_TIMESTAMP() AND deleted IS NULL AND id = ?`
+
+	result, err := m.DB.Exec(stmt, expires.UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrNoRecord
+	}
+
+	return nil
+}
+
+// Return snippet data by ID whatever its visibility is and count the view.
+// The snippet row is locked while the view is counted, so when the limit of
+// views is reached the snippet expires before anyone else can read it.
 func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	// SQL request for getting data of one record
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-    WHERE expires > UTC_TIMESTAMP() AND id = ?`
+	stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE s.expires > UTC_TIMESTAMP() AND s.deleted IS NULL AND s.id = ?
+    FOR UPDATE`
 
 	// Use QueryRow() for executing SQL request passing unreliable variable ID like a placeholder
-	row := m.DB.QueryRow(stmt, id)
+	row := tx.QueryRow(stmt, id)
 
 	// Initialise the pointer to new struct Snippet
 	s := &models.Snippet{}
 
 	// Use row.Scan() to copy the value from every sql.Row field to Snippet Struct
-	err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	err = row.Scan(snippetFields(s)...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, models.ErrNoRecord
@@ -54,18 +247,403 @@ func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
 		}
 	}
 
+	// Only views of snippets with a limit are counted here. The assignments
+	// are evaluated from left to right, so expires sees the new views value.
+	if s.MaxViews > 0 {
+		stmt = `UPDATE snippets
+    SET views = views + 1, expires = IF(views >= max_views, UTC_TIMESTAMP(), expires)
+    WHERE id = ?`
+		_, err = tx.Exec(stmt, s.ID)
+		if err != nil {
+			return nil, err
+		}
+		s.Views++
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the snippet tags and files
+	err = m.details(s)
+	if err != nil {
+		return nil, err
+	}
+
 	// If all ok return Snippet object
 	return s, nil
 }
 
-// Return last 10 snippets
+// Return snippet data by ID like Get does but without counting a view
+func (m *SnippetModel) Peek(id int) (*models.Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE s.expires > UTC_TIMESTAMP() AND s.deleted IS NULL AND s.id = ?`
+
+	s := &models.Snippet{}
+	err := m.DB.QueryRow(stmt, id).Scan(snippetFields(s)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+
+	err = m.details(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Return data of the unlisted snippet with the given slug without counting
+// a view
+func (m *SnippetModel) GetBySlug(slug string) (*models.Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE s.expires > UTC_TIMESTAMP() AND s.deleted IS NULL AND s.slug = ?`
+
+	s := &models.Snippet{}
+	err := m.DB.QueryRow(stmt, slug).Scan(snippetFields(s)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+
+	err = m.details(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Load the tags, files, number of forks and total views of the snippet
+func (m *SnippetModel) details(s *models.Snippet) error {
+	var err error
+
+	s.Tags, err = m.tags(s.ID)
+	if err != nil {
+		return err
+	}
+
+	s.Files, err = m.files(s.ID)
+	if err != nil {
+		return err
+	}
+
+	stmt := `SELECT COUNT(*) FROM snippets
+    WHERE expires > UTC_TIMESTAMP() AND deleted IS NULL AND forked_from = ?`
+	err = m.DB.QueryRow(stmt, s.ID).Scan(&s.Forks)
+	if err != nil {
+		return err
+	}
+
+	stmt = `SELECT COALESCE(SUM(views), 0) FROM snippet_views WHERE snippet_id = ?`
+	return m.DB.QueryRow(stmt, s.ID).Scan(&s.TotalViews)
+}
+
+// Return last 10 public snippets
 func (m *SnippetModel) Latest() ([]*models.Snippet, error) {
 	// SQL request we wanted to execute
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-    WHERE expires > UTC_TIMESTAMP() ORDER BY created DESC LIMIT 10`
+	stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE s.expires > UTC_TIMESTAMP() AND s.deleted IS NULL AND s.visibility = 'public'
+    ORDER BY s.created DESC LIMIT 10`
+
+	return m.query(stmt)
+}
+
+// Return a page of not expired public snippets ordered from newest to oldest,
+// skipping the first offset ones, and the total number of such snippets
+func (m *SnippetModel) Archive(limit, offset int) ([]*models.Snippet, int, error) {
+	var total int
+	stmt := `SELECT COUNT(*) FROM snippets
+    WHERE expires > UTC_TIMESTAMP() AND deleted IS NULL AND visibility = 'public'`
+	err := m.DB.QueryRow(stmt).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stmt = `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE s.expires > UTC_TIMESTAMP() AND s.deleted IS NULL AND s.visibility = 'public'
+    ORDER BY s.created DESC, s.id DESC LIMIT ? OFFSET ?`
+
+	snippets, err := m.query(stmt, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return snippets, total, nil
+}
+
+// Return a page of not expired public snippets without a passphrase or a limit
+// of views matching the full-text query, ranked by relevance, and the total number of matching
+// snippets
+func (m *SnippetModel) Search(query string, limit, offset int) ([]*models.Snippet, int, error) {
+	var total int
+	stmt := `SELECT COUNT(*) FROM snippets
+    WHERE expires > UTC_TIMESTAMP() AND deleted IS NULL AND visibility = 'public'
+    AND hashed_passphrase IS NULL AND max_views IS NULL AND MATCH(title, content) AGAINST(? IN NATURAL LANGUAGE MODE)`
+	err := m.DB.QueryRow(stmt, query).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stmt = `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE s.expires > UTC_TIMESTAMP() AND s.deleted IS NULL AND s.visibility = 'public'
+    AND s.hashed_passphrase IS NULL AND s.max_views IS NULL AND MATCH(s.title, s.content) AGAINST(? IN NATURAL LANGUAGE MODE)
+    ORDER BY MATCH(s.title, s.content) AGAINST(? IN NATURAL LANGUAGE MODE) DESC, s.id DESC
+    LIMIT ? OFFSET ?`
+
+	snippets, err := m.query(stmt, query, query, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return snippets, total, nil
+}
+
+// Check the passphrase of the protected snippet with the given ID. Return
+// ErrInvalidCredentials if it doesn't match and ErrNoRecord if there is no
+// such protected snippet.
+func (m *SnippetModel) Unlock(id int, passphrase string) error {
+	var hashedPassphrase []byte
+
+	stmt := `SELECT hashed_passphrase FROM snippets
+    WHERE expires > UTC_TIMESTAMP() AND deleted IS NULL AND hashed_passphrase IS NOT NULL AND id = ?`
+	err := m.DB.QueryRow(stmt, id).Scan(&hashedPassphrase)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrNoRecord
+	} else if err != nil {
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword(hashedPassphrase, []byte(passphrase))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return models.ErrInvalidCredentials
+	}
+
+	return err
+}
+
+// Return all not expired snippets created by the user with the given ID
+func (m *SnippetModel) ByUser(userID int) ([]*models.Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE s.expires > UTC_TIMESTAMP() AND s.deleted IS NULL AND s.user_id = ?
+    ORDER BY s.created DESC`
+
+	return m.query(stmt, userID)
+}
+
+// Move the snippet with the given ID to the trash
+func (m *SnippetModel) Delete(id int) error {
+	stmt := `UPDATE snippets SET deleted = UTC_TIMESTAMP() WHERE deleted IS NULL AND id = ?`
+
+	_, err := m.DB.Exec(stmt, id)
+	return err
+}
+
+// Take the snippet with the given ID out of the trash of the user with the
+// given ID. Return ErrNoRecord if the user has no such snippet in the trash.
+func (m *SnippetModel) Restore(id, userID int) error {
+	stmt := `UPDATE snippets SET deleted = NULL
+    WHERE deleted IS NOT NULL AND id = ? AND user_id = ?`
+
+	result, err := m.DB.Exec(stmt, id, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrNoRecord
+	}
+
+	return nil
+}
+
+// Return the snippets in the trash of the user with the given ID
+func (m *SnippetModel) Trash(userID int) ([]*models.Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + `, s.deleted
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE s.deleted IS NOT NULL AND s.user_id = ? ORDER BY s.deleted DESC`
+
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var snippets []*models.Snippet
+
+	for rows.Next() {
+		s := &models.Snippet{}
+		err = rows.Scan(append(snippetFields(s), &s.Deleted)...)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// Permanently remove snippets which have been in the trash longer than
+// retention. Return the number of removed snippets.
+func (m *SnippetModel) Purge(retention time.Duration) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	seconds := int64(retention.Seconds())
+
+	stmt := `DELETE t FROM snippet_tags t INNER JOIN snippets s ON s.id = t.snippet_id
+    WHERE s.deleted IS NOT NULL AND s.deleted < DATE_SUB(UTC_TIMESTAMP(), INTERVAL ? SECOND)`
+
+	_, err = tx.Exec(stmt, seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt = `DELETE r FROM snippet_revisions r INNER JOIN snippets s ON s.id = r.snippet_id
+    WHERE s.deleted IS NOT NULL AND s.deleted < DATE_SUB(UTC_TIMESTAMP(), INTERVAL ? SECOND)`
+
+	_, err = tx.Exec(stmt, seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt = `DELETE f FROM snippet_files f INNER JOIN snippets s ON s.id = f.snippet_id
+    WHERE s.deleted IS NOT NULL AND s.deleted < DATE_SUB(UTC_TIMESTAMP(), INTERVAL ? SECOND)`
+
+	_, err = tx.Exec(stmt, seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt = `DELETE st FROM stars st INNER JOIN snippets s ON s.id = st.snippet_id
+    WHERE s.deleted IS NOT NULL AND s.deleted < DATE_SUB(UTC_TIMESTAMP(), INTERVAL ? SECOND)`
+
+	_, err = tx.Exec(stmt, seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt = `DELETE c FROM comments c INNER JOIN snippets s ON s.id = c.snippet_id
+    WHERE s.deleted IS NOT NULL AND s.deleted < DATE_SUB(UTC_TIMESTAMP(), INTERVAL ? SECOND)`
+
+	_, err = tx.Exec(stmt, seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt = `DELETE v FROM snippet_views v INNER JOIN snippets s ON s.id = v.snippet_id
+    WHERE s.deleted IS NOT NULL AND s.deleted < DATE_SUB(UTC_TIMESTAMP(), INTERVAL ? SECOND)`
+
+	_, err = tx.Exec(stmt, seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt = `DELETE cs FROM collection_snippets cs INNER JOIN snippets s ON s.id = cs.snippet_id
+    WHERE s.deleted IS NOT NULL AND s.deleted < DATE_SUB(UTC_TIMESTAMP(), INTERVAL ? SECOND)`
+
+	_, err = tx.Exec(stmt, seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt = `DELETE FROM snippets
+    WHERE deleted IS NOT NULL AND deleted < DATE_SUB(UTC_TIMESTAMP(), INTERVAL ? SECOND)`
+
+	result, err := tx.Exec(stmt, seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), tx.Commit()
+}
+
+// Permanently remove at most limit expired snippets together with their
+// tags, files, stars, comments, views, collection entries and history. Return the number of removed snippets.
+func (m *SnippetModel) DeleteExpired(limit int) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Lock the batch, so it's removed as a whole
+	stmt := `SELECT id FROM snippets WHERE expires <= UTC_TIMESTAMP() ORDER BY id LIMIT ? FOR UPDATE`
+	rows, err := tx.Query(stmt, limit)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var ids []interface{}
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	in := "(?" + strings.Repeat(", ?", len(ids)-1) + ")"
+	for _, stmt := range []string{
+		`DELETE FROM snippet_tags WHERE snippet_id IN ` + in,
+		`DELETE FROM snippet_revisions WHERE snippet_id IN ` + in,
+		`DELETE FROM snippet_files WHERE snippet_id IN ` + in,
+		`DELETE FROM stars WHERE snippet_id IN ` + in,
+		`DELETE FROM comments WHERE snippet_id IN ` + in,
+		`DELETE FROM snippet_views WHERE snippet_id IN ` + in,
+		`DELETE FROM collection_snippets WHERE snippet_id IN ` + in,
+		`DELETE FROM snippets WHERE id IN ` + in,
+	} {
+		_, err = tx.Exec(stmt, ids...)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(ids), tx.Commit()
+}
 
+// Execute SQL request returning snippet rows and scan them to the slice
+func (m *SnippetModel) query(stmt string, args ...interface{}) ([]*models.Snippet, error) {
 	// Use Query() for execute SQL request
-	rows, err := m.DB.Query(stmt)
+	rows, err := m.DB.Query(stmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -78,7 +656,7 @@ func (m *SnippetModel) Latest() ([]*models.Snippet, error) {
 	for rows.Next() {
 		s := &models.Snippet{}
 		// Use row.Scan() to copy the value from every sql.Row field to Snippet Struct
-		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+		err = rows.Scan(snippetFields(s)...)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,134 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+func TestSnippetModelUpdateDeleted(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	db, teardown := newTestDB(t)
+	defer teardown()
+
+	m := SnippetModel{db}
+
+	s := &models.Snippet{
+		UserID:     1,
+		Title:      "An old silent pond",
+		Content:    "An old silent pond...",
+		Language:   "plaintext",
+		Visibility: models.Public,
+		Tags:       []string{"haiku"},
+		Expires:    models.Never,
+	}
+	id, err := m.Insert(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An unchanged snippet is still updated
+	s.ID = id
+	err = m.Update(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The trashed snippet keeps its tags and history
+	err = m.Delete(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Tags = nil
+	err = m.Update(s)
+	if err != models.ErrNoRecord {
+		t.Errorf("want %v; got %v", models.ErrNoRecord, err)
+	}
+
+	revisions, err := m.Revisions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 {
+		t.Errorf("want 2 revisions; got %d", len(revisions))
+	}
+	tags, err := m.tags(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 {
+		t.Errorf("want 1 tag; got %v", tags)
+	}
+}
+
+func TestSnippetModelStarredUnlisted(t *testing.T) {
+	if testing.Short() {
+		t.Skip("mysql: skipping integration test")
+	}
+
+	db, teardown := newTestDB(t)
+	defer teardown()
+
+	m := SnippetModel{db}
+	users := UserModel{db}
+
+	// Bob's snippet is starred by Alice from setup.sql, then made unlisted
+	err := users.Insert("Bob", "bob@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &models.Snippet{
+		UserID:     2,
+		Title:      "Lightning flash",
+		Content:    "Lightning flash...",
+		Language:   "plaintext",
+		Visibility: models.Public,
+		Expires:    models.Never,
+	}
+	s.ID, err = m.Insert(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Star(s.ID, 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	starred, err := m.Starred(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(starred) != 1 {
+		t.Fatalf("want 1 starred snippet; got %d", len(starred))
+	}
+
+	s.Visibility = models.Unlisted
+	err = m.Update(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	starred, err = m.Starred(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(starred) != 0 {
+		t.Errorf("want no starred snippets; got %v", starred)
+	}
+
+	// The author still sees their own unlisted snippet
+	err = m.Star(s.ID, 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	starred, err = m.Starred(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(starred) != 1 {
+		t.Errorf("want 1 starred snippet; got %d", len(starred))
+	}
+}
@@ -0,0 +1,40 @@
+package mysql
+
+import (
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+// Star the snippet for the user if star is true, otherwise unstar it. Setting
+// the state the snippet already has is not an error.
+func (m *SnippetModel) Star(id, userID int, star bool) error {
+	stmt := `DELETE FROM stars WHERE snippet_id = ? AND user_id = ?`
+	if star {
+		stmt = `INSERT IGNORE INTO stars (snippet_id, user_id, created) VALUES(?, ?, UTC_TIMESTAMP())`
+	}
+
+	_, err := m.DB.Exec(stmt, id, userID)
+	return err
+}
+
+// Return true if the user starred the snippet
+func (m *SnippetModel) IsStarred(id, userID int) (bool, error) {
+	var starred bool
+	stmt := `SELECT EXISTS(SELECT true FROM stars WHERE snippet_id = ? AND user_id = ?)`
+
+	err := m.DB.QueryRow(stmt, id, userID).Scan(&starred)
+	return starred, err
+}
+
+// Return the not expired snippets starred by the user, the latest starred
+// first. Snippets of other users which were made unlisted after they were
+// starred are left out, their links would give away the new slug.
+func (m *SnippetModel) Starred(userID int) ([]*models.Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + `
+    FROM stars st INNER JOIN snippets s ON s.id = st.snippet_id
+    INNER JOIN users u ON u.id = s.user_id
+    WHERE s.expires > UTC_TIMESTAMP() AND s.deleted IS NULL AND st.user_id = ?
+    AND (s.visibility = 'public' OR s.user_id = ?)
+    ORDER BY st.created DESC`
+
+	return m.query(stmt, userID, userID)
+}
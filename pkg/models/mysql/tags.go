@@ -0,0 +1,109 @@
+package mysql
+
+import (
+	"database/sql"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+// Maximum number of tags shown in the tag cloud
+const tagCloudSize = 30
+
+// Return a page of not expired public snippets with the given tag ordered from
+// newest to oldest and the total number of such snippets
+func (m *SnippetModel) ByTag(tag string, limit, offset int) ([]*models.Snippet, int, error) {
+	var total int
+	stmt := `SELECT COUNT(*) FROM snippets s INNER JOIN snippet_tags t ON t.snippet_id = s.id
+    WHERE s.expires > UTC_TIMESTAMP() AND s.deleted IS NULL AND s.visibility = 'public' AND t.tag = ?`
+	err := m.DB.QueryRow(stmt, tag).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stmt = `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    INNER JOIN snippet_tags t ON t.snippet_id = s.id
+    WHERE s.expires > UTC_TIMESTAMP() AND s.deleted IS NULL AND s.visibility = 'public' AND t.tag = ?
+    ORDER BY s.created DESC, s.id DESC LIMIT ? OFFSET ?`
+
+	snippets, err := m.query(stmt, tag, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return snippets, total, nil
+}
+
+// Return the most used tags of not expired public snippets in alphabetical order
+// together with the number of snippets for every tag
+func (m *SnippetModel) TagCloud() ([]*models.Tag, error) {
+	stmt := `SELECT tag, n FROM (
+        SELECT t.tag, COUNT(*) AS n FROM snippet_tags t
+        INNER JOIN snippets s ON s.id = t.snippet_id
+        WHERE s.expires > UTC_TIMESTAMP() AND s.deleted IS NULL AND s.visibility = 'public'
+        GROUP BY t.tag ORDER BY n DESC, t.tag LIMIT ?
+    ) cloud ORDER BY tag`
+
+	rows, err := m.DB.Query(stmt, tagCloudSize)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var tags []*models.Tag
+
+	for rows.Next() {
+		t := &models.Tag{}
+		err = rows.Scan(&t.Name, &t.Count)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// Return tags of the snippet with the given ID in alphabetical order
+func (m *SnippetModel) tags(id int) ([]string, error) {
+	rows, err := m.DB.Query(`SELECT tag FROM snippet_tags WHERE snippet_id = ? ORDER BY tag`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var tags []string
+
+	for rows.Next() {
+		var tag string
+		err = rows.Scan(&tag)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// Link the snippet with the given ID to every tag within the transaction
+func insertTags(tx *sql.Tx, id int, tags []string) error {
+	for _, tag := range tags {
+		_, err := tx.Exec(`INSERT INTO snippet_tags (snippet_id, tag) VALUES(?, ?)`, id, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
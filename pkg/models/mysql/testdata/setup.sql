@@ -1,14 +1,123 @@
 CREATE TABLE
     snippets (
         id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+        user_id INTEGER NOT NULL,
         title VARCHAR(100) NOT NULL,
         content TEXT NOT NULL,
+        language VARCHAR(20) NOT NULL DEFAULT 'plaintext',
+        visibility VARCHAR(10) NOT NULL DEFAULT 'public',
+        slug CHAR(22),
+        hashed_passphrase CHAR(60),
+        views INTEGER NOT NULL DEFAULT 0,
+        max_views INTEGER,
+        forked_from INTEGER,
         created DATETIME NOT NULL,
-        expires DATETIME NOT NULL
+        expires DATETIME NOT NULL,
+        deleted DATETIME
     );
 
 CREATE INDEX idx_snippets_created ON snippets (created);
 
+CREATE INDEX idx_snippets_user_id ON snippets (user_id);
+
+CREATE INDEX idx_snippets_expires ON snippets (expires);
+
+CREATE INDEX idx_snippets_forked_from ON snippets (forked_from);
+
+CREATE UNIQUE INDEX idx_snippets_slug ON snippets (slug);
+
+CREATE FULLTEXT INDEX idx_snippets_fulltext ON snippets (title, content);
+
+CREATE TABLE
+    snippet_tags (
+        snippet_id INTEGER NOT NULL,
+        tag VARCHAR(30) NOT NULL,
+        PRIMARY KEY (snippet_id, tag)
+    );
+
+CREATE INDEX idx_snippet_tags_tag ON snippet_tags (tag);
+
+CREATE TABLE
+    snippet_files (
+        id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+        snippet_id INTEGER NOT NULL,
+        position INTEGER NOT NULL,
+        filename VARCHAR(100) NOT NULL,
+        language VARCHAR(20) NOT NULL,
+        content TEXT NOT NULL
+    );
+
+CREATE UNIQUE INDEX idx_snippet_files_snippet_id ON snippet_files (snippet_id, position);
+
+CREATE TABLE
+    stars (
+        user_id INTEGER NOT NULL,
+        snippet_id INTEGER NOT NULL,
+        created DATETIME NOT NULL,
+        PRIMARY KEY (user_id, snippet_id)
+    );
+
+CREATE INDEX idx_stars_snippet_id ON stars (snippet_id);
+
+CREATE TABLE
+    comments (
+        id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+        snippet_id INTEGER NOT NULL,
+        parent_id INTEGER,
+        user_id INTEGER NOT NULL,
+        content TEXT NOT NULL,
+        created DATETIME NOT NULL,
+        updated DATETIME
+    );
+
+CREATE INDEX idx_comments_snippet_id ON comments (snippet_id, created);
+
+CREATE INDEX idx_comments_parent_id ON comments (parent_id);
+
+CREATE TABLE
+    snippet_views (
+        snippet_id INTEGER NOT NULL,
+        day DATE NOT NULL,
+        views INTEGER NOT NULL,
+        PRIMARY KEY (snippet_id, day)
+    );
+
+CREATE TABLE
+    collections (
+        id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+        user_id INTEGER NOT NULL,
+        title VARCHAR(100) NOT NULL,
+        description TEXT NOT NULL,
+        created DATETIME NOT NULL
+    );
+
+CREATE INDEX idx_collections_user_id ON collections (user_id);
+
+CREATE TABLE
+    collection_snippets (
+        collection_id INTEGER NOT NULL,
+        snippet_id INTEGER NOT NULL,
+        position INTEGER NOT NULL,
+        PRIMARY KEY (collection_id, snippet_id)
+    );
+
+CREATE INDEX idx_collection_snippets_position ON collection_snippets (collection_id, position);
+
+CREATE INDEX idx_collection_snippets_snippet_id ON collection_snippets (snippet_id);
+
+CREATE TABLE
+    snippet_revisions (
+        id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+        snippet_id INTEGER NOT NULL,
+        user_id INTEGER NOT NULL,
+        title VARCHAR(100) NOT NULL,
+        content TEXT NOT NULL,
+        language VARCHAR(20) NOT NULL,
+        created DATETIME NOT NULL
+    );
+
+CREATE INDEX idx_snippet_revisions_snippet_id ON snippet_revisions (snippet_id);
+
 CREATE TABLE
     users (
         id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
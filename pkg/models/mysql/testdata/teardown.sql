@@ -1,2 +1,10 @@
 DROP TABLE users;
+DROP TABLE stars;
+DROP TABLE comments;
+DROP TABLE snippet_views;
+DROP TABLE collections;
+DROP TABLE collection_snippets;
+DROP TABLE snippet_revisions;
+DROP TABLE snippet_files;
+DROP TABLE snippet_tags;
 DROP TABLE snippets;
\ No newline at end of file
@@ -0,0 +1,59 @@
+package mysql
+
+import (
+	"strings"
+	"time"
+
+	"github.com/alekslesik/snippetbox.learn/pkg/models"
+)
+
+// Add the views to the daily view counts of the snippets in one statement
+func (m *SnippetModel) RecordViews(views []*models.ViewCount) error {
+	if len(views) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, 3*len(views))
+	for _, v := range views {
+		args = append(args, v.SnippetID, v.Day.UTC().Format("2006-01-02"), v.Views)
+	}
+
+	stmt := `INSERT INTO snippet_views (snippet_id, day, views)
+    VALUES (?, ?, ?)` + strings.Repeat(", (?, ?, ?)", len(views)-1) + `
+    ON DUPLICATE KEY UPDATE views = views + VALUES(views)`
+
+	_, err := m.DB.Exec(stmt, args...)
+	return err
+}
+
+// Return the daily view counts of the snippet from the day of since on,
+// oldest first. Days without views are left out.
+func (m *SnippetModel) DailyViews(id int, since time.Time) ([]*models.ViewCount, error) {
+	stmt := `SELECT snippet_id, day, views FROM snippet_views
+    WHERE snippet_id = ? AND day >= ?
+    ORDER BY day`
+
+	rows, err := m.DB.Query(stmt, id, since.UTC().Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var views []*models.ViewCount
+
+	for rows.Next() {
+		v := &models.ViewCount{}
+		err = rows.Scan(&v.SnippetID, &v.Day, &v.Views)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return views, nil
+}
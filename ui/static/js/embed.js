@@ -0,0 +1,31 @@
+// Embed a snippet into another site:
+//
+// <script src="https://snippetbox.example.com/static/js/embed.js"
+//     data-snippet="https://snippetbox.example.com/snippet/1" async></script>
+//
+// The script is replaced by a frame with the embed page of the snippet, which
+// grows to the height of the snippet.
+(function() {
+	var script = document.currentScript;
+	if (!script || !script.getAttribute("data-snippet")) {
+		return;
+	}
+
+	var frame = document.createElement("iframe");
+	frame.src = script.getAttribute("data-snippet") + "/embed";
+	frame.title = "Snippet";
+	frame.width = "100%";
+	frame.height = "300";
+	frame.style.border = "none";
+	script.parentNode.replaceChild(frame, script);
+
+	var origin = new URL(frame.src, window.location.href).origin;
+	window.addEventListener("message", function(e) {
+		if (e.origin !== origin || e.source !== frame.contentWindow) {
+			return;
+		}
+		if (e.data && e.data.snippetboxHeight) {
+			frame.height = String(e.data.snippetboxHeight);
+		}
+	});
+})();
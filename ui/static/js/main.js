@@ -5,4 +5,99 @@ for (var i = 0; i < navLinks.length; i++) {
 		link.classList.add("live");
 		break;
 	}
-}
\ No newline at end of file
+}
+
+// Copy the value of the element with the ID from data-copy to the clipboard
+var copyButtons = document.querySelectorAll("button[data-copy]");
+for (var i = 0; i < copyButtons.length; i++) {
+	copyButtons[i].addEventListener("click", function(e) {
+		var button = e.currentTarget;
+		var source = document.getElementById(button.getAttribute("data-copy"));
+		navigator.clipboard.writeText(source.value).then(function() {
+			button.textContent = "Copied";
+		});
+	});
+}
+
+// Add an empty file entry to the snippet form by cloning the last one, and
+// remove file entries. The last entry is only cleared, so there's always one
+// to clone.
+var addFile = document.querySelector("button.add-file");
+if (addFile) {
+	addFile.addEventListener("click", function() {
+		var files = document.querySelectorAll("fieldset.file");
+		var last = files[files.length - 1];
+		var entry = last.cloneNode(true);
+		var labels = entry.querySelectorAll("label.error");
+		for (var i = 0; i < labels.length; i++) {
+			labels[i].remove();
+		}
+		entry.querySelector("input").value = "";
+		entry.querySelector("textarea").value = "";
+		last.parentNode.insertBefore(entry, addFile);
+	});
+
+	addFile.parentNode.addEventListener("click", function(e) {
+		if (!e.target.classList.contains("remove-file")) {
+			return;
+		}
+		var entry = e.target.closest("fieldset.file");
+		if (document.querySelectorAll("fieldset.file").length > 1) {
+			entry.remove();
+		} else {
+			entry.querySelector("input").value = "";
+			entry.querySelector("textarea").value = "";
+		}
+	});
+}
+
+// Highlight the lines selected by the URL fragment: #L10 or #L10-L20 for the
+// snippet content and #name-L10-L20 for its file with the name
+var lineRX = /^#(.*?L)(\d+)(?:-L(\d+))?$/;
+
+function highlightLines() {
+	var marked = document.querySelectorAll(".lines .hl");
+	for (var i = 0; i < marked.length; i++) {
+		marked[i].classList.remove("hl");
+	}
+
+	var m = decodeURIComponent(window.location.hash).match(lineRX);
+	if (!m) {
+		return;
+	}
+	var from = parseInt(m[2], 10);
+	var to = m[3] ? parseInt(m[3], 10) : from;
+	for (var n = from; n <= to; n++) {
+		var number = document.getElementById(m[1] + n);
+		if (!number) {
+			break;
+		}
+		number.parentNode.classList.add("hl");
+		// There is no element with the ID of a range to scroll to
+		if (n == from && m[3]) {
+			number.scrollIntoView();
+		}
+	}
+}
+
+if (document.querySelector(".lines")) {
+	highlightLines();
+	window.addEventListener("hashchange", highlightLines);
+
+	// Shift-click on a line number selects the range from the selected line
+	document.addEventListener("click", function(e) {
+		var link = e.target.closest(".lines a");
+		if (!link || !e.shiftKey) {
+			return;
+		}
+		var selected = decodeURIComponent(window.location.hash).match(lineRX);
+		var clicked = decodeURIComponent(link.getAttribute("href")).match(lineRX);
+		if (!selected || !clicked || selected[1] != clicked[1]) {
+			return;
+		}
+		e.preventDefault();
+		var a = parseInt(selected[2], 10);
+		var b = parseInt(clicked[2], 10);
+		window.location.hash = "#" + clicked[1] + Math.min(a, b) + "-L" + Math.max(a, b);
+	});
+}